@@ -2,7 +2,9 @@ package processing
 
 import (
 	"fmt"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 var ErrClosed = fmt.Errorf("already closed")
@@ -13,72 +15,476 @@ var ErrClosed = fmt.Errorf("already closed")
 // Once this capacity is exceeded any further Channel.Send
 // operation is blocked until a message is received by the
 // a Channel.Receive operation.
+// A channel with capacity 0 is unbuffered. Here, Channel.Send
+// blocks until the message is taken by a Channel.Receive
+// operation. A Select case for such a channel is only ready,
+// if there is an operation blocked in the opposite operation,
+// or waiting in a Select with an opposite case.
+// If an operation is cancelled while being blocked in
+// Channel.Send or Channel.Receive, the cancellation error
+// is returned.
+// The Timeout variants give up with ErrTimeout, if the
+// operation cannot be completed in the given time.
+// The Try variants never block. They report whether the
+// message could be sent or received without waiting.
+// Once a channel is closed, sending fails with ErrClosed, and
+// receiving provides the still buffered messages before it
+// fails with ErrClosed. Blocked operations are woken up.
+// Range and All iterate over the received messages until
+// the channel is closed and drained.
 type Channel[T any] interface {
 	Send(Operation, T) error
+	SendTimeout(Operation, T, time.Duration) error
+	TrySend(Operation, T) (bool, error)
 	Receive(Operation) (T, error)
+	ReceiveTimeout(Operation, time.Duration) (T, error)
+	TryReceive(Operation) (T, bool, error)
 	Close() error
+
+	Range(Operation, func(T) bool) error
+	All(Operation) Seq[T]
+
+	Info() ElementInfo
 }
 
+// Seq is an iterator over a sequence of values. It calls yield
+// for every value until the sequence is exhausted or yield
+// returns false.
+type Seq[T any] func(yield func(T) bool)
+
 type channel[T any] struct {
-	monitor  Monitor
+	monitor  *monitor
 	send     Condition
 	receive  Condition
 	capacity int
 	size     int
 	first    int
 	buffer   []T
+	pending  map[Operation]T        // messages of blocked senders of an unbuffered channel
+	offers   map[*selector]offer[T] // messages offered by waiting selects
 
-	closed atomic.Bool
+	lock     sync.Mutex // synchronizes closing with waiting operations
+	closed   atomic.Bool
+	level    atomic.Int64 // fill level for inspection
+	watchers watchers
 }
 
 func NewChannel[T any](capacity int, names ...string) Channel[T] {
+	return NewChannelWithQueue[T](capacity, nil, names...)
+}
+
+// NewChannelWithQueue creates a Channel using the given queuing
+// policy for blocked senders and receivers.
+func NewChannelWithQueue[T any](capacity int, f QueueFactory, names ...string) Channel[T] {
+	size := capacity
+	if size == 0 {
+		// slot for passing a message to a receiver
+		size = 1
+	}
 	return &channel[T]{
-		monitor:  newMonitor("channel", names...),
-		send:     NewCondition("send"),
-		receive:  NewCondition("receive"),
+		monitor:  newMonitor(f, "channel", names...),
+		send:     NewConditionWithQueue(f, "send"),
+		receive:  NewConditionWithQueue(f, "receive"),
 		capacity: capacity,
-		buffer:   make([]T, capacity),
+		buffer:   make([]T, size),
+		pending:  map[Operation]T{},
+		offers:   map[*selector]offer[T]{},
 	}
 }
 
+// offer is a message offered by a Select case.
+type offer[T any] struct {
+	index int
+	msg   T
+}
+
 func (c *channel[T]) Send(op Operation, t T) error {
+	return c.sendUntil(op, t, time.Time{})
+}
+
+func (c *channel[T]) SendTimeout(op Operation, t T, d time.Duration) error {
+	return c.sendUntil(op, t, time.Now().Add(d))
+}
+
+func (c *channel[T]) sendUntil(op Operation, t T, deadline time.Time) error {
 	if c.closed.Load() {
 		return ErrClosed
 	}
-	c.monitor.Lock(op)
-	defer c.monitor.Unlock()
+	if err := c.monitor.enter(op, deadline); err != nil {
+		return err
+	}
+	if c.capacity == 0 {
+		return c.handOver(op, t, deadline)
+	}
 
 	for c.size >= c.capacity {
-		c.monitor.Wait(c.send)
+		if err := c.await(c.send, deadline); err != nil {
+			c.monitor.Unlock()
+			return err
+		}
 	}
-	c.buffer[(c.first+c.size)%c.capacity] = t
-	c.size++
+	if c.closed.Load() {
+		c.monitor.Unlock()
+		return ErrClosed
+	}
+	c.put(t)
 
-	c.monitor.Notify(c.receive)
+	// the message has been transferred, even if reacquiring
+	// the monitor after the notification is interrupted.
+	c.monitor.NotifyE(c.receive)
+	c.monitor.Unlock()
 	return nil
 }
 
+// TrySend sends a message, if this is possible without blocking.
+// If the channel is actually used by another operation, this
+// is treated like a full channel.
+func (c *channel[T]) TrySend(op Operation, t T) (bool, error) {
+	if c.closed.Load() {
+		return false, ErrClosed
+	}
+	if !c.monitor.tryEnter(op) {
+		return false, nil
+	}
+	if c.closed.Load() {
+		c.monitor.Unlock()
+		return false, ErrClosed
+	}
+	if c.deliver(t) {
+		return true, nil
+	}
+	if c.size >= c.capacity {
+		c.monitor.Unlock()
+		return false, nil
+	}
+	c.put(t)
+
+	c.monitor.release(c.receive)
+	return true, nil
+}
+
 func (c *channel[T]) Receive(op Operation) (T, error) {
-	c.monitor.Lock(op)
-	defer c.monitor.Unlock()
+	return c.receiveUntil(op, time.Time{})
+}
+
+func (c *channel[T]) ReceiveTimeout(op Operation, d time.Duration) (T, error) {
+	return c.receiveUntil(op, time.Now().Add(d))
+}
+
+func (c *channel[T]) receiveUntil(op Operation, deadline time.Time) (T, error) {
+	var zero T
+
+	if err := c.monitor.enter(op, deadline); err != nil {
+		return zero, err
+	}
+	if c.capacity == 0 {
+		return c.takeOver(op, deadline)
+	}
 
 	for c.size == 0 {
-		if c.closed.Load() {
-			var zero T
-			return zero, ErrClosed
+		err := c.await(c.receive, deadline)
+		if err == ErrClosed && c.size > 0 {
+			// drain messages still in the buffer
+			break
+		}
+		if err != nil {
+			c.monitor.Unlock()
+			return zero, err
 		}
-		c.monitor.Wait(c.receive)
 	}
-	t := c.buffer[c.first]
-	c.size--
-	c.first = (c.first + 1) % c.capacity
-	c.monitor.Notify(c.send)
+	t := c.take()
+	// the message has been transferred, even if reacquiring
+	// the monitor after the notification is interrupted.
+	c.monitor.NotifyE(c.send)
+	c.monitor.Unlock()
 	return t, nil
 }
 
+// TryReceive receives a message, if this is possible without blocking.
+// If the channel is actually used by another operation, this
+// is treated like an empty channel.
+func (c *channel[T]) TryReceive(op Operation) (T, bool, error) {
+	var zero T
+
+	if !c.monitor.tryEnter(op) {
+		return zero, false, nil
+	}
+	if t, ok := c.collect(); ok {
+		return t, true, nil
+	}
+	if c.size == 0 {
+		c.monitor.Unlock()
+		if c.closed.Load() {
+			return zero, false, ErrClosed
+		}
+		return zero, false, nil
+	}
+	t := c.take()
+	c.monitor.release(c.send)
+	return t, true, nil
+}
+
+// Range calls f for every received message until the channel
+// is closed and drained, or f returns false. In both cases nil
+// is returned. If the operation is interrupted while waiting
+// for a message, the error is returned.
+func (c *channel[T]) Range(op Operation, f func(T) bool) error {
+	for {
+		t, err := c.Receive(op)
+		if err == ErrClosed {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !f(t) {
+			return nil
+		}
+	}
+}
+
+// All provides the received messages as sequence. The sequence
+// ends, when the channel is closed and drained. Errors cannot be
+// reported, so the sequence also ends, if the operation is
+// interrupted. Use Range to get informed about this situation.
+func (c *channel[T]) All(op Operation) Seq[T] {
+	return func(yield func(T) bool) {
+		c.Range(op, yield)
+	}
+}
+
+// Close closes the channel. Blocked senders are woken up with
+// ErrClosed, blocked receivers receive the messages still
+// in the buffer, afterwards they get ErrClosed.
 func (c *channel[T]) Close() error {
+	c.lock.Lock()
 	if c.closed.Swap(true) {
+		c.lock.Unlock()
 		return ErrClosed
 	}
+	for _, cond := range []Condition{c.send, c.receive} {
+		for n := dequeue(cond.waiting); n != nil; n = dequeue(cond.waiting) {
+			n.UnblockE(ErrClosed)
+		}
+	}
+	c.lock.Unlock()
+	c.watchers.signal()
 	return nil
 }
+
+// await waits for the condition, if the channel is not closed.
+// Otherwise, ErrClosed is returned. Like for an interrupted wait,
+// the monitor is still held in this case.
+func (c *channel[T]) await(cond Condition, deadline time.Time) error {
+	c.lock.Lock()
+	if c.closed.Load() {
+		c.lock.Unlock()
+		return ErrClosed
+	}
+	return c.monitor.await(cond, deadline, &c.lock)
+}
+
+// put adds a message to the buffer.
+// It must be called while holding the monitor.
+func (c *channel[T]) put(t T) {
+	c.buffer[(c.first+c.size)%len(c.buffer)] = t
+	c.size++
+	c.level.Store(int64(c.size))
+	c.watchers.signal()
+}
+
+// take removes a message from the buffer.
+// It must be called while holding the monitor.
+func (c *channel[T]) take() T {
+	t := c.buffer[c.first]
+	c.size--
+	c.level.Store(int64(c.size))
+	c.first = (c.first + 1) % len(c.buffer)
+	c.watchers.signal()
+	return t
+}
+
+// pollSend sends a message, if there is space in the buffer.
+// In contrast to TrySend it waits for the monitor.
+func (c *channel[T]) pollSend(op Operation, t T) (bool, error) {
+	if c.closed.Load() {
+		return true, ErrClosed
+	}
+	if err := c.monitor.enter(op, time.Time{}); err != nil {
+		return false, err
+	}
+	if c.deliver(t) {
+		return true, nil
+	}
+	if c.size >= c.capacity {
+		c.monitor.Unlock()
+		return false, nil
+	}
+	c.put(t)
+	c.monitor.release(c.receive)
+	return true, nil
+}
+
+// pollReceive receives a message, if the buffer is not empty.
+// In contrast to TryReceive it waits for the monitor.
+func (c *channel[T]) pollReceive(op Operation) (T, bool, error) {
+	var zero T
+
+	if err := c.monitor.enter(op, time.Time{}); err != nil {
+		return zero, false, err
+	}
+	if t, ok := c.collect(); ok {
+		return t, true, nil
+	}
+	if c.size == 0 {
+		c.monitor.Unlock()
+		if c.closed.Load() {
+			return zero, true, ErrClosed
+		}
+		return zero, false, nil
+	}
+	t := c.take()
+	c.monitor.release(c.send)
+	return t, true, nil
+}
+
+// handOver sends a message on an unbuffered channel.
+// It must be called while holding the monitor. The message is
+// either passed to a waiting receiver, or the sender waits until
+// a receiver takes it.
+func (c *channel[T]) handOver(op Operation, t T, deadline time.Time) error {
+	if c.closed.Load() {
+		c.monitor.Unlock()
+		return ErrClosed
+	}
+	if c.deliver(t) {
+		return nil
+	}
+
+	c.lock.Lock()
+	c.pending[op] = t
+	c.lock.Unlock()
+	c.watchers.signal()
+
+	if err := c.await(c.send, deadline); err != nil {
+		// the sender is not queued anymore, so
+		// no receiver can take the message.
+		c.lock.Lock()
+		delete(c.pending, op)
+		c.lock.Unlock()
+		c.monitor.Unlock()
+		return err
+	}
+	// the receiver took the message and passed the monitor.
+	c.monitor.Unlock()
+	return nil
+}
+
+// takeOver receives a message on an unbuffered channel.
+// It must be called while holding the monitor. The message
+// is either taken from a waiting sender, or the receiver waits
+// until a sender passes it.
+func (c *channel[T]) takeOver(op Operation, deadline time.Time) (T, error) {
+	for c.size == 0 {
+		if t, ok := c.collect(); ok {
+			return t, nil
+		}
+		c.watchers.signal()
+		if err := c.await(c.receive, deadline); err != nil {
+			var zero T
+			c.monitor.Unlock()
+			return zero, err
+		}
+	}
+	t := c.take()
+	c.monitor.Unlock()
+	return t, nil
+}
+
+// deliver passes a message of an unbuffered channel together
+// with the monitor to a waiting receiver.
+// It must be called while holding the monitor.
+func (c *channel[T]) deliver(t T) bool {
+	if c.capacity > 0 {
+		return false
+	}
+	n := c.monitor.next(c.receive)
+	if n == nil {
+		return false
+	}
+	c.put(t)
+	n.Unblock() // pass monitor to the receiver
+	return true
+}
+
+// collect takes the message of a waiting sender of an
+// unbuffered channel and passes the monitor to the sender,
+// or releases it for a message offered by a Select.
+// It must be called while holding the monitor.
+func (c *channel[T]) collect() (T, bool) {
+	var zero T
+
+	if c.capacity > 0 {
+		return zero, false
+	}
+	n := c.monitor.next(c.send)
+	if n == nil {
+		t, ok := c.claim()
+		if ok {
+			c.monitor.Unlock()
+		}
+		return t, ok
+	}
+	c.lock.Lock()
+	t := c.pending[n]
+	delete(c.pending, n)
+	c.lock.Unlock()
+	n.Unblock() // pass monitor to the sender
+	c.watchers.signal()
+	return t, true
+}
+
+// offer offers the message of a waiting Select to receivers of
+// an unbuffered channel. It reports false, if a receiver is already
+// waiting or the channel is closed, so the Select must poll again.
+func (c *channel[T]) offer(s *selector, index int, t T) bool {
+	if c.capacity > 0 {
+		return true
+	}
+	c.lock.Lock()
+	if c.closed.Load() || c.receive.waiting.Len() > 0 {
+		c.lock.Unlock()
+		return false
+	}
+	c.offers[s] = offer[T]{index, t}
+	c.lock.Unlock()
+	c.watchers.signalOthers(s)
+	return true
+}
+
+// withdraw withdraws the offer of a Select.
+func (c *channel[T]) withdraw(s *selector) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.offers, s)
+}
+
+// claim takes a message offered by a waiting Select.
+// It must be called while holding the monitor.
+func (c *channel[T]) claim() (T, bool) {
+	var zero T
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed.Load() {
+		return zero, false
+	}
+	for s, o := range c.offers {
+		if s.claim(o.index) {
+			delete(c.offers, s)
+			return o.msg, true
+		}
+	}
+	return zero, false
+}
@@ -1,7 +1,9 @@
 package processing_test
 
 import (
+	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -156,3 +158,313 @@ var _ = Describe("channel", func() {
 		fmt.Printf("channel done\n")
 	})
 })
+
+var _ = Describe("channel with timeout", func() {
+	var sched processing.Scheduler
+	var ch processing.Channel[string]
+
+	BeforeEach(func() {
+		sched = processing.New(1)
+		ch = processing.NewChannel[string](1)
+	})
+
+	It("times out receive and send", func() {
+		var rerr, serr1, serr2 error
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			_, rerr = ch.ReceiveTimeout(op, 100*time.Millisecond)
+			serr1 = ch.SendTimeout(op, "msg-1", 100*time.Millisecond)
+			serr2 = ch.SendTimeout(op, "msg-2", 100*time.Millisecond)
+		}, sched).Start()
+
+		Expect(e1.WaitE(nil)).To(Succeed())
+		Expect(rerr).To(Equal(processing.ErrTimeout))
+		Expect(serr1).To(Succeed())
+		Expect(serr2).To(Equal(processing.ErrTimeout))
+		Expect(sched.BlockedCount()).To(Equal(0))
+
+		var msg string
+		e2 := processing.NewExecution(func(op processing.Operation) {
+			msg, rerr = ch.ReceiveTimeout(op, 100*time.Millisecond)
+		}, sched).Start()
+		Expect(e2.WaitE(nil)).To(Succeed())
+		Expect(rerr).To(Succeed())
+		Expect(msg).To(Equal("msg-1"))
+	})
+})
+
+var _ = Describe("channel without blocking", func() {
+	It("tries to send and receive", func() {
+		sched := processing.New(1)
+		ch := processing.NewChannel[string](1)
+
+		type result struct {
+			msg string
+			ok  bool
+			err error
+		}
+		var results []result
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			m, ok, err := ch.TryReceive(op)
+			results = append(results, result{m, ok, err})
+			ok, err = ch.TrySend(op, "msg-1")
+			results = append(results, result{"", ok, err})
+			ok, err = ch.TrySend(op, "msg-2")
+			results = append(results, result{"", ok, err})
+			m, ok, err = ch.TryReceive(op)
+			results = append(results, result{m, ok, err})
+			ch.Close()
+			m, ok, err = ch.TryReceive(op)
+			results = append(results, result{m, ok, err})
+		}, sched).Start()
+
+		Expect(e1.WaitE(nil)).To(Succeed())
+		Expect(results).To(Equal([]result{
+			{"", false, nil},
+			{"", true, nil},
+			{"", false, nil},
+			{"msg-1", true, nil},
+			{"", false, processing.ErrClosed},
+		}))
+	})
+})
+
+var _ = Describe("closing channel", func() {
+	var sched processing.Scheduler
+	var ch processing.Channel[string]
+
+	BeforeEach(func() {
+		sched = processing.New(2)
+		ch = processing.NewChannel[string](1)
+	})
+
+	It("wakes blocked receivers", func() {
+		var rerr error
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			_, rerr = ch.Receive(op)
+		}, sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+
+		Expect(ch.Close()).To(Succeed())
+		Expect(e1.WaitE(nil)).To(Succeed())
+		Expect(rerr).To(Equal(processing.ErrClosed))
+		Expect(sched.BlockedCount()).To(Equal(0))
+	})
+
+	It("wakes blocked senders", func() {
+		var serr1, serr2 error
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			serr1 = ch.Send(op, "msg-1")
+			serr2 = ch.Send(op, "msg-2")
+		}, sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+
+		Expect(ch.Close()).To(Succeed())
+		Expect(e1.WaitE(nil)).To(Succeed())
+		Expect(serr1).To(Succeed())
+		Expect(serr2).To(Equal(processing.ErrClosed))
+		Expect(sched.BlockedCount()).To(Equal(0))
+	})
+
+	It("drains buffered messages", func() {
+		ch = processing.NewChannel[string](2)
+		var msgs []string
+		var rerr error
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			ch.Send(op, "msg-1")
+			ch.Send(op, "msg-2")
+			ch.Close()
+			for {
+				var msg string
+				msg, rerr = ch.Receive(op)
+				if rerr != nil {
+					return
+				}
+				msgs = append(msgs, msg)
+			}
+		}, sched).Start()
+
+		Expect(e1.WaitE(nil)).To(Succeed())
+		Expect(msgs).To(Equal([]string{"msg-1", "msg-2"}))
+		Expect(rerr).To(Equal(processing.ErrClosed))
+		Expect(ch.Send(nil, "msg-3")).To(Equal(processing.ErrClosed))
+		Expect(ch.Close()).To(Equal(processing.ErrClosed))
+	})
+})
+
+var _ = Describe("unbuffered channel", func() {
+	var sched processing.Scheduler
+	var ch processing.Channel[string]
+
+	BeforeEach(func() {
+		sched = processing.New(1)
+		ch = processing.NewChannel[string](0, "unbuffered")
+	})
+
+	It("blocks sender until message is received", func() {
+		var sent atomic.Bool
+		sender := processing.NewExecution(func(op processing.Operation) {
+			Expect(ch.Send(op, "msg-1")).To(Succeed())
+			sent.Store(true)
+		}, sched, "sender").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+		Expect(sched.BlockedOperations()).To(Equal([]string{"execution:sender"}))
+		Expect(sent.Load()).To(BeFalse())
+
+		var msg string
+		receiver := processing.NewExecution(func(op processing.Operation) {
+			msg, _ = ch.Receive(op)
+		}, sched, "receiver").Start()
+
+		processing.NewDependencyTrigger(nil, sender, receiver).Wait(nil)
+		Expect(sent.Load()).To(BeTrue())
+		Expect(msg).To(Equal("msg-1"))
+	})
+
+	It("passes message to waiting receiver", func() {
+		var msgs []string
+		receiver := processing.NewExecution(func(op processing.Operation) {
+			for i := 0; i < 3; i++ {
+				msg, err := ch.Receive(op)
+				Expect(err).To(Succeed())
+				msgs = append(msgs, msg)
+			}
+		}, sched, "receiver").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+
+		sender := processing.NewExecution(func(op processing.Operation) {
+			for i := 1; i <= 3; i++ {
+				Expect(ch.Send(op, fmt.Sprintf("msg-%d", i))).To(Succeed())
+			}
+		}, sched, "sender").Start()
+
+		processing.NewDependencyTrigger(nil, sender, receiver).Wait(nil)
+		Expect(msgs).To(Equal([]string{"msg-1", "msg-2", "msg-3"}))
+	})
+
+	It("withdraws message of timed out sender", func() {
+		var ok, tok bool
+		var trerr, serr, terr error
+		e := processing.NewExecution(func(op processing.Operation) {
+			ok, trerr = ch.TrySend(op, "msg-1")
+			serr = ch.SendTimeout(op, "msg-2", 100*time.Millisecond)
+			_, tok, terr = ch.TryReceive(op)
+		}, sched).Start()
+
+		Expect(e.WaitE(nil)).To(Succeed())
+		Expect(ok).To(BeFalse())
+		Expect(trerr).To(Succeed())
+		Expect(serr).To(Equal(processing.ErrTimeout))
+		Expect(tok).To(BeFalse())
+		Expect(terr).To(Succeed())
+		Expect(ch.Info().Size).To(Equal(0))
+	})
+
+	It("wakes blocked sender on close", func() {
+		var serr error
+		e := processing.NewExecution(func(op processing.Operation) {
+			serr = ch.Send(op, "msg-1")
+		}, sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+
+		Expect(ch.Close()).To(Succeed())
+		Expect(e.WaitE(nil)).To(Succeed())
+		Expect(serr).To(Equal(processing.ErrClosed))
+	})
+
+	It("selects blocked sender", func() {
+		other := processing.NewChannel[string](0, "other")
+		sender := processing.NewExecution(func(op processing.Operation) {
+			Expect(ch.Send(op, "msg-1")).To(Succeed())
+		}, sched, "sender").Start()
+
+		var msg string
+		var index int
+		receiver := processing.NewExecution(func(op processing.Operation) {
+			index, _ = processing.Select(op, processing.ReceiveCase(other, &msg), processing.ReceiveCase(ch, &msg))
+		}, sched, "receiver").Start()
+
+		processing.NewDependencyTrigger(nil, sender, receiver).Wait(nil)
+		Expect(index).To(Equal(1))
+		Expect(msg).To(Equal("msg-1"))
+	})
+})
+
+var _ = Describe("channel iteration", func() {
+	var sched processing.Scheduler
+	var ch processing.Channel[int]
+
+	BeforeEach(func() {
+		sched = processing.New(2)
+		ch = processing.NewChannel[int](1)
+	})
+
+	send := func(n int) processing.Execution {
+		return processing.NewExecution(func(op processing.Operation) {
+			for i := 1; i <= n; i++ {
+				if ch.Send(op, i) != nil {
+					return
+				}
+			}
+			ch.Close()
+		}, sched, "sender").Start()
+	}
+
+	It("ranges until channel is closed and drained", func() {
+		var values []int
+		var err error
+		receiver := processing.NewExecution(func(op processing.Operation) {
+			err = ch.Range(op, func(v int) bool {
+				values = append(values, v)
+				return true
+			})
+		}, sched, "receiver").Start()
+		sender := send(5)
+
+		processing.NewDependencyTrigger(nil, sender, receiver).Wait(nil)
+		Expect(err).To(Succeed())
+		Expect(values).To(Equal([]int{1, 2, 3, 4, 5}))
+	})
+
+	It("stops ranging", func() {
+		var values []int
+		var err error
+		receiver := processing.NewExecution(func(op processing.Operation) {
+			err = ch.Range(op, func(v int) bool {
+				values = append(values, v)
+				return v < 2
+			})
+			ch.Close()
+		}, sched, "receiver").Start()
+		sender := send(5)
+
+		processing.NewDependencyTrigger(nil, sender, receiver).Wait(nil)
+		Expect(err).To(Succeed())
+		Expect(values).To(Equal([]int{1, 2}))
+	})
+
+	It("reports interruption", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		var err error
+		e := processing.NewExecutionWithContext(ctx, func(op processing.Operation) {
+			err = ch.Range(op, func(v int) bool { return true })
+		}, sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+		cancel()
+		e.Wait(nil)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("provides sequence", func() {
+		sum := 0
+		receiver := processing.NewExecution(func(op processing.Operation) {
+			ch.All(op)(func(v int) bool {
+				sum += v
+				return true
+			})
+		}, sched, "receiver").Start()
+		sender := send(4)
+
+		processing.NewDependencyTrigger(nil, sender, receiver).Wait(nil)
+		Expect(sum).To(Equal(10))
+	})
+})
@@ -0,0 +1,236 @@
+package processing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrDeadlock = fmt.Errorf("deadlock detected")
+
+// Deadlock describes a cycle in the wait-for graph of blocked
+// operations. Every operation waits for the next one, the last
+// one waits for the first one. Queues contains the names of the
+// queues the operations are blocked in.
+type Deadlock struct {
+	Operations []string
+	Queues     []string
+
+	ops []State
+}
+
+func (d Deadlock) String() string {
+	var b strings.Builder
+	for i, n := range d.Operations {
+		fmt.Fprintf(&b, "%s (%s) -> ", n, d.Queues[i])
+	}
+	if len(d.Operations) > 0 {
+		b.WriteString(d.Operations[0])
+	}
+	return b.String()
+}
+
+// DeadlockHandler is called for every deadlock detected
+// by a Scheduler.
+type DeadlockHandler func(Deadlock)
+
+// WithDeadlockDetection enables the detection of deadlocks whenever
+// an operation is blocked. Detected deadlocks are reported to the
+// given handler (if not nil). If fail is set, the involved operations
+// are interrupted with an error wrapping ErrDeadlock.
+// The wait-for graph covers operations waiting for a known operation:
+// the holder of a mutex, a monitor or a reader/writer mutex held by a
+// writer, or an execution to be finished. Operations waiting for
+// conditions, channels, triggers or semaphores might be woken up by
+// any other operation or Go routine and are therefore never part of a
+// deadlock. If all operations are blocked, this is reported by the
+// stall detection. Operations waiting with a timeout are never deadlocked.
+func WithDeadlockDetection(h DeadlockHandler, fail bool) Option {
+	return func(s *scheduler) {
+		s.deadlocks = h
+		s.failDeadlocks = fail
+		s.detectDeadlocks = true
+	}
+}
+
+// waitNode is a blocked operation in the wait-for graph.
+type waitNode struct {
+	queue   Queue
+	waitFor []State
+}
+
+// waitGraph is the wait-for graph of blocked operations.
+type waitGraph struct {
+	nodes map[State]*waitNode
+	ops   []State // sorted by name
+}
+
+// _waitForGraph builds the wait-for graph of the deadlocked operations.
+// Starting with all operations waiting for known operations, operations
+// are removed from the graph as long as they wait for an operation not
+// in the graph.
+// The wait-for functions only use internal locks of the synchronization
+// primitives, which are never held while acquiring the scheduler lock.
+func (s *scheduler) _waitForGraph() *waitGraph {
+	nodes := map[State]*waitNode{}
+	for o := range s.operations {
+		q, f := o._waitingFor()
+		if q == nil || f == nil || o.timed {
+			continue
+		}
+		n := &waitNode{queue: q}
+		for _, w := range f() {
+			if t, ok := w.(State); ok {
+				n.waitFor = append(n.waitFor, t)
+			}
+		}
+		nodes[o] = n
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for o, n := range nodes {
+			free := true
+			for _, w := range n.waitFor {
+				if nodes[w] != nil {
+					free = false
+					break
+				}
+			}
+			if free {
+				delete(nodes, o)
+				changed = true
+			}
+		}
+	}
+
+	g := &waitGraph{nodes: nodes}
+	for o := range nodes {
+		g.ops = append(g.ops, o)
+	}
+	sort.Slice(g.ops, func(i, j int) bool { return g.ops[i].Name() < g.ops[j].Name() })
+	return g
+}
+
+// successors returns the deadlocked operations the given one is waiting for.
+func (g *waitGraph) successors(o State) []State {
+	var result []State
+	for _, w := range g.nodes[o].waitFor {
+		if g.nodes[w] != nil {
+			result = append(result, w)
+		}
+	}
+	return result
+}
+
+// findCycle searches a cycle in the wait-for graph starting
+// and ending with the given operation.
+func (g *waitGraph) findCycle(start State) []State {
+	if g.nodes[start] == nil {
+		return nil
+	}
+	var path []State
+	visited := map[State]bool{}
+
+	var visit func(o State) bool
+	visit = func(o State) bool {
+		visited[o] = true
+		path = append(path, o)
+		for _, w := range g.successors(o) {
+			if w == start || (!visited[w] && visit(w)) {
+				return true
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	if visit(start) {
+		return path
+	}
+	return nil
+}
+
+// deadlock describes the given cycle starting with the
+// operation with the lowest name.
+func (g *waitGraph) deadlock(cycle []State) Deadlock {
+	first := 0
+	for i, o := range cycle {
+		if o.Name() < cycle[first].Name() {
+			first = i
+		}
+	}
+	cycle = append(cycle[first:], cycle[:first]...)
+
+	d := Deadlock{}
+	for _, o := range cycle {
+		d.Operations = append(d.Operations, o.Name())
+		d.Queues = append(d.Queues, g.nodes[o].queue.Name())
+		d.ops = append(d.ops, o)
+	}
+	return d
+}
+
+// FindDeadlocks returns the actually detected deadlocks.
+func (s *scheduler) FindDeadlocks() []Deadlock {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	g := s._waitForGraph()
+
+	var result []Deadlock
+	found := map[State]bool{}
+	for _, o := range g.ops {
+		if found[o] {
+			continue
+		}
+		cycle := g.findCycle(o)
+		for _, c := range cycle {
+			found[c] = true
+		}
+		if cycle != nil {
+			result = append(result, g.deadlock(cycle))
+		}
+	}
+	return result
+}
+
+// detectDeadlock checks, whether the given operation is
+// part of a deadlock and handles it according to the
+// scheduler configuration.
+func (s *scheduler) detectDeadlock(b State) {
+	s.lock.Lock()
+	g := s._waitForGraph()
+	cycle := g.findCycle(b)
+	if cycle == nil || s._reported(cycle) {
+		s.lock.Unlock()
+		return
+	}
+	d := g.deadlock(cycle)
+	if s.failDeadlocks {
+		// interrupt under the same lock to report the
+		// deadlock only once.
+		err := fmt.Errorf("%w: %s", ErrDeadlock, d)
+		for _, o := range d.ops {
+			s._interrupt(o, err)
+		}
+	}
+	s.lock.Unlock()
+
+	if s.deadlocks != nil {
+		s.deadlocks(d)
+	}
+}
+
+// _reported checks, whether the cycle has already been reported
+// for the actual blocks of the operations, and marks it as reported.
+// This way concurrently blocked operations report a deadlock only once.
+func (s *scheduler) _reported(cycle []State) bool {
+	reported := true
+	for _, o := range cycle {
+		if o.deadlocked != o.blocks {
+			reported = false
+		}
+		o.deadlocked = o.blocks
+	}
+	return reported
+}
@@ -0,0 +1,155 @@
+package processing_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/processing/pkg/processing"
+)
+
+const FAIL = Step("fail")
+
+var _ = Describe("deadlock detection", func() {
+	var results *LockResults
+	var m1, m2 processing.Mutex
+
+	BeforeEach(func() {
+		results = &LockResults{}
+		m1 = processing.NewMutex("m1")
+		m2 = processing.NewMutex("m2")
+	})
+
+	crossLock := func(sched processing.Scheduler) (processing.Execution, processing.Execution) {
+		t1 := processing.NewTrigger()
+		t2 := processing.NewTrigger()
+		locked := processing.NewDependencyTrigger(nil, t1, t2)
+
+		lock := func(name string, first, second processing.Mutex, t processing.Trigger) processing.OperationFunction {
+			return func(op processing.Operation) {
+				first.Lock(op)
+				t.Arm()
+				t.Trigger()
+				locked.Wait(op)
+				if err := second.LockE(op); err != nil {
+					results.Add(FAIL, name)
+					first.Unlock()
+					return
+				}
+				results.Add(LOCK, name)
+				second.Unlock()
+				first.Unlock()
+			}
+		}
+		e1 := processing.NewExecution(lock("e1", m1, m2, t1), sched, "e1").Start()
+		e2 := processing.NewExecution(lock("e2", m2, m1, t2), sched, "e2").Start()
+		return e1, e2
+	}
+
+	It("finds deadlocks", func() {
+		sched := processing.New(2)
+		crossLock(sched)
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(2))
+
+		var deadlocks []processing.Deadlock
+		Eventually(func() []processing.Deadlock {
+			deadlocks = sched.FindDeadlocks()
+			return deadlocks
+		}, 5*time.Second).Should(HaveLen(1))
+		Expect(deadlocks[0].Operations).To(Equal([]string{"execution:e1", "execution:e2"}))
+		Expect(deadlocks[0].Queues).To(Equal([]string{"mutex:m2", "mutex:m1"}))
+		Expect(deadlocks[0].String()).To(Equal("execution:e1 (mutex:m2) -> execution:e2 (mutex:m1) -> execution:e1"))
+		sched.Close()
+	})
+
+	It("fails deadlocked operations", func() {
+		var reported []processing.Deadlock
+		sched := processing.New(2, processing.WithDeadlockDetection(func(d processing.Deadlock) {
+			reported = append(reported, d)
+		}, true))
+		e1, e2 := crossLock(sched)
+
+		processing.NewDependencyTrigger(nil, e1, e2).Wait(nil)
+		Expect(results.list).To(ConsistOf(FAIL.R("e1"), FAIL.R("e2")))
+		Expect(reported).To(HaveLen(1))
+		Expect(sched.FindDeadlocks()).To(BeEmpty())
+	})
+
+	It("ignores operations waiting for running operations", func() {
+		sched := processing.New(2)
+		release := make(chan struct{})
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			m1.Lock(op)
+			<-release
+			m1.Unlock()
+		}, sched, "e1").Start()
+		Eventually(m1.Info).Should(HaveField("Locked", true))
+		e2 := processing.NewExecution(func(op processing.Operation) {
+			m1.Lock(op)
+			m1.Unlock()
+		}, sched, "e2").Start()
+		e3 := processing.NewExecution(func(op processing.Operation) {
+			e2.Wait(op)
+		}, sched, "e3").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(2))
+
+		Expect(sched.FindDeadlocks()).To(BeEmpty())
+		close(release)
+		processing.NewDependencyTrigger(nil, e1, e2, e3).Wait(nil)
+	})
+
+	It("ignores operations waiting for triggers", func() {
+		sched := processing.New(2)
+		release := processing.NewTrigger()
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			m1.Lock(op)
+			release.Wait(op)
+			m1.Unlock()
+		}, sched, "e1").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+		e2 := processing.NewExecution(func(op processing.Operation) {
+			m1.Lock(op)
+			m1.Unlock()
+		}, sched, "e2").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(2))
+
+		Expect(sched.FindDeadlocks()).To(BeEmpty())
+		release.Arm()
+		release.Trigger()
+		processing.NewDependencyTrigger(nil, e1, e2).Wait(nil)
+	})
+
+	It("fails operations deadlocked by waiting for an execution", func() {
+		var reported []processing.Deadlock
+		sched := processing.New(2, processing.WithDeadlockDetection(func(d processing.Deadlock) {
+			reported = append(reported, d)
+		}, true))
+
+		var e2 processing.Execution
+		started := processing.NewTrigger()
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			m1.Lock(op)
+			defer m1.Unlock()
+			started.Wait(op)
+			if e2.WaitE(op) != nil {
+				results.Add(FAIL, "e1")
+			}
+		}, sched, "e1").Start()
+		Eventually(m1.Info, 5*time.Second).Should(HaveField("Locked", true))
+		e2 = processing.NewExecution(func(op processing.Operation) {
+			if m1.LockE(op) != nil {
+				results.Add(FAIL, "e2")
+				return
+			}
+			m1.Unlock()
+		}, sched, "e2").Start()
+		started.Arm()
+		started.Trigger()
+
+		processing.NewDependencyTrigger(nil, e1, e2).Wait(nil)
+		Expect(results.list).To(ConsistOf(FAIL.R("e1"), FAIL.R("e2")))
+		Expect(reported).To(HaveLen(1))
+		Expect(reported[0].String()).To(Equal("execution:e1 (trigger) -> execution:e2 (mutex:m1) -> execution:e1"))
+	})
+})
@@ -0,0 +1,169 @@
+// Package debug provides an http.Handler rendering the actual
+// state of registered schedulers and synchronization elements.
+// Similar to net/http/pprof it is intended to be used on a local
+// debug endpoint of a service, for example
+//
+//	h := debug.NewHandler()
+//	h.RegisterScheduler("main", sched)
+//	h.Register(mutex, trigger)
+//	http.Handle("/debug/processing", h)
+//
+// By default a human-readable text is rendered, the query
+// parameter format=json selects a JSON document.
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mandelsoft/processing/pkg/processing"
+)
+
+// State is the document rendered by the Handler.
+type State struct {
+	Schedulers map[string]processing.Snapshot `json:"schedulers"`
+	Elements   []processing.ElementInfo       `json:"elements"`
+}
+
+// Handler is an http.Handler rendering the State of the
+// registered schedulers and elements.
+type Handler struct {
+	lock       sync.Mutex
+	schedulers map[string]processing.Scheduler
+	elements   []processing.Inspectable
+}
+
+var _ http.Handler = (*Handler)(nil)
+
+func NewHandler() *Handler {
+	return &Handler{schedulers: map[string]processing.Scheduler{}}
+}
+
+// RegisterScheduler registers a scheduler under the given name.
+// A nil scheduler removes the registration.
+func (h *Handler) RegisterScheduler(name string, s processing.Scheduler) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if s == nil {
+		delete(h.schedulers, name)
+	} else {
+		h.schedulers[name] = s
+	}
+}
+
+// Register registers synchronization elements like mutexes,
+// triggers or channels.
+func (h *Handler) Register(elems ...processing.Inspectable) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.elements = append(h.elements, elems...)
+}
+
+// State provides the actual state of the registered
+// schedulers and elements.
+func (h *Handler) State() State {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	state := State{
+		Schedulers: map[string]processing.Snapshot{},
+		Elements:   []processing.ElementInfo{},
+	}
+	for n, s := range h.schedulers {
+		state.Schedulers[n] = s.Snapshot()
+	}
+	for _, e := range h.elements {
+		state.Elements = append(state.Elements, e.Info())
+	}
+	return state
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	state := h.State()
+
+	switch f := r.URL.Query().Get("format"); f {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(state)
+	case "", "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		WriteText(w, state)
+	default:
+		http.Error(w, fmt.Sprintf("unknown format %q", f), http.StatusBadRequest)
+	}
+}
+
+// WriteText writes a human-readable representation of the state.
+func WriteText(w io.Writer, state State) {
+	var names []string
+	for n := range state.Schedulers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		s := state.Schedulers[n]
+		closed := ""
+		if s.Closed {
+			closed = ", closed"
+		}
+		fmt.Fprintf(w, "scheduler %s: limit %d, running %d, ready %d, blocked %d%s\n",
+			n, s.Limit, s.Running, s.Ready, s.Blocked, closed)
+		for _, o := range s.Operations {
+			fmt.Fprintf(w, "  %s: %s", o.Name, o.State)
+			if o.Queue != "" {
+				fmt.Fprintf(w, " in %s", o.Queue)
+			}
+			if !o.BlockedSince.IsZero() {
+				fmt.Fprintf(w, " for %s", s.Time.Sub(o.BlockedSince).Round(time.Millisecond))
+			}
+			if len(o.WaitingFor) > 0 {
+				fmt.Fprintf(w, " waiting for %s", strings.Join(o.WaitingFor, ", "))
+			}
+			fmt.Fprintf(w, " (priority %d)\n", o.Priority)
+		}
+	}
+
+	if len(state.Elements) > 0 {
+		fmt.Fprintf(w, "elements:\n")
+	}
+	for _, e := range state.Elements {
+		var attrs []string
+		if e.Locked {
+			attrs = append(attrs, "locked")
+		}
+		if len(e.Holders) > 0 {
+			attrs = append(attrs, "held by "+strings.Join(e.Holders, ", "))
+		}
+		if e.Readers > 0 {
+			attrs = append(attrs, fmt.Sprintf("%d readers", e.Readers))
+		}
+		if e.Capacity > 0 {
+			attrs = append(attrs, fmt.Sprintf("%d/%d used", e.Size, e.Capacity))
+		}
+		if e.Dependencies > 0 {
+			attrs = append(attrs, fmt.Sprintf("%d pending dependencies", e.Dependencies))
+		}
+		if e.Armed {
+			attrs = append(attrs, "armed")
+		}
+		if e.Triggered {
+			attrs = append(attrs, "triggered")
+		}
+		if e.Closed {
+			attrs = append(attrs, "closed")
+		}
+		attrs = append(attrs, fmt.Sprintf("%d waiting", e.Waiting))
+		fmt.Fprintf(w, "  %s: %s\n", e.Name, strings.Join(attrs, ", "))
+	}
+}
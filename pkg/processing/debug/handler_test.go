@@ -0,0 +1,85 @@
+package debug_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/processing/pkg/processing"
+	"github.com/mandelsoft/processing/pkg/processing/debug"
+)
+
+var _ = Describe("debug handler", func() {
+	var sched processing.Scheduler
+	var server *httptest.Server
+	var release processing.Trigger
+	var done processing.Trigger
+
+	BeforeEach(func() {
+		sched = processing.New(1)
+		release = processing.NewTrigger("release")
+		m := processing.NewMutex("m")
+		c := processing.NewChannel[int](2, "c")
+
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			m.Lock(op)
+			c.Send(op, 1)
+			release.Wait(op)
+			m.Unlock()
+		}, sched, "e1").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+		e2 := processing.NewExecution(func(op processing.Operation) {
+			m.Lock(op)
+			m.Unlock()
+		}, sched, "e2").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(2))
+		done = processing.NewDependencyTrigger(nil, e1, e2)
+
+		h := debug.NewHandler()
+		h.RegisterScheduler("main", sched)
+		h.Register(m, c, release)
+		server = httptest.NewServer(h)
+	})
+
+	AfterEach(func() {
+		server.Close()
+		release.Arm()
+		release.Trigger()
+		done.Wait(nil)
+	})
+
+	get := func(query string) string {
+		resp, err := server.Client().Get(server.URL + query)
+		Expect(err).To(Succeed())
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		Expect(err).To(Succeed())
+		return string(data)
+	}
+
+	It("renders text", func() {
+		text := get("")
+		Expect(text).To(MatchRegexp(`(?m)^scheduler main: limit 1, running 0, ready 0, blocked 2$`))
+		Expect(text).To(MatchRegexp(`(?m)^  execution:e1: Blocked in trigger:release for .* \(priority 0\)$`))
+		Expect(text).To(MatchRegexp(`(?m)^  execution:e2: Blocked in mutex:m for .* waiting for execution:e1 \(priority 0\)$`))
+		Expect(text).To(ContainSubstring("  mutex:m: locked, held by execution:e1, 1 waiting\n"))
+		Expect(text).To(ContainSubstring("  channel:c: 1/2 used, 0 waiting\n"))
+		Expect(text).To(ContainSubstring("  trigger:release: 1 waiting\n"))
+	})
+
+	It("renders json", func() {
+		var state debug.State
+		Expect(json.Unmarshal([]byte(get("?format=json")), &state)).To(Succeed())
+		Expect(state.Schedulers).To(HaveKey("main"))
+		ops := state.Schedulers["main"].Operations
+		Expect(ops).To(HaveLen(2))
+		Expect(ops[1].WaitingFor).To(Equal([]string{"execution:e1"}))
+		Expect(state.Elements).To(HaveLen(3))
+		Expect(state.Elements[0].Holders).To(Equal([]string{"execution:e1"}))
+		Expect(state.Elements[1].Size).To(Equal(1))
+	})
+})
@@ -1,7 +1,10 @@
 package processing
 
 import (
+	"context"
+	"fmt"
 	"sync"
+	"time"
 )
 
 // Execution represent the scheduled execution of
@@ -23,43 +26,135 @@ type ReleaseFunction func()
 // MUST only be used by the OperationFunction (or better, by the Go routine
 // used to execute the OperationFunction). It should never be stored
 // in any object and shared with other Go routines.
+// Block and Unblock can be used to implement synchronization
+// primitives. BlockE and UnblockE are variants additionally
+// transporting a reason for the wake-up, for example a
+// cancellation. BlockTimeout gives up waiting after the given
+// duration with ErrTimeout.
 type Operation interface {
+	ID() uint64
+	Name() string
+	Context() context.Context
+	Priority() int
+	SetPriority(int)
 	Block(Queue, ReleaseFunction)
+	BlockE(Queue, ReleaseFunction) error
+	BlockTimeout(Queue, ReleaseFunction, time.Duration) error
 	Unblock()
+	UnblockE(error)
 	Preempt()
 	_unblock()
+	_setWaitFor(func() []Operation)
+	_blockUntil(Queue, ReleaseFunction, time.Time) error
+	_blockUninterruptible(Queue, ReleaseFunction)
 
 	_removedFromQueue(q Queue)
 	_addToQueue(Queue, bool)
+	_queuedFor() time.Duration
 }
 
 func NewExecution(f OperationFunction, s Scheduler, names ...string) Execution {
-	return newExecution(f, s, nil, "execution", names...)
+	return newExecution(context.Background(), f, s, nil, "execution", names...)
 }
 
-func newExecution(f OperationFunction, s Scheduler, self interface{}, typ string, names ...string) Execution {
+// NewExecutionWithContext creates an Execution bound to the given context.
+// If the context is cancelled, the operation is woken up from any
+// synchronization primitive it is blocked in, and all further blocking
+// attempts fail immediately. The context is available for the
+// OperationFunction via Operation.Context().
+func NewExecutionWithContext(ctx context.Context, f OperationFunction, s Scheduler, names ...string) Execution {
+	return newExecution(ctx, f, s, nil, "execution", names...)
+}
+
+func newExecution(ctx context.Context, f OperationFunction, s Scheduler, self interface{}, typ string, names ...string) Execution {
 	e := &execution{function: f}
 	if self == nil {
 		self = e
 	}
-	e.state = s.new(self, typ, names...)
+	e.state = s.new(ctx, self, typ, names...)
 	return e
 }
 
+var ErrStarted = fmt.Errorf("execution already started")
+
+// Start starts the execution. It returns nil, if the
+// execution has already been started. If the scheduler has
+// been shut down, the execution is returned already finished
+// as Cancelled with ErrShutdown.
 func (e *execution) Start() Execution {
+	if e.StartE() == ErrStarted {
+		return nil
+	}
+	return e
+}
+
+// StartE starts the execution. If the scheduler has been
+// shut down, ErrShutdown is returned and the execution
+// is finished without calling the OperationFunction.
+func (e *execution) StartE() error {
+	err := e.start()
+	if err == ErrShutdown {
+		e.state._setErr(err)
+		e.state.skip(Cancelled)
+	}
+	return err
+}
+
+func (e *execution) start() error {
 	e.lock.Lock()
 	defer e.lock.Unlock()
 
 	if e.function == nil {
-		return nil
+		return ErrStarted
 	}
-	e.state.scheduler.start(e.state, e.function)
+	err := e.state.scheduler.start(e.state, e.function)
 	e.function = nil
-	return e
+	return err
 }
 
+// SetPriority sets the scheduling priority of the execution.
+// Ready operations with a higher priority are scheduled first.
+func (e *execution) SetPriority(p int) {
+	e.state.SetPriority(p)
+}
+
+func (e *execution) Priority() int {
+	return e.state.Priority()
+}
+
+// Wait waits for the execution to be finished.
+// If the operation is cancelled while waiting, Wait panics
+// with the error to stop the operation. Use WaitE to handle
+// this situation.
 func (e *execution) Wait(o Operation) {
-	e.state.done.Wait(o)
+	must(e.WaitE(o))
+}
+
+// WaitE waits for the execution to be finished.
+// It returns an error, if the wait is interrupted.
+func (e *execution) WaitE(o Operation) error {
+	if o == nil {
+		return e.state.done.WaitE(o)
+	}
+	o._setWaitFor(e.waitFor)
+	defer o._setWaitFor(nil)
+	return e.state.done.WaitE(o)
+}
+
+func (e *execution) waitFor() []Operation {
+	return []Operation{e.state}
+}
+
+// Err returns the error the execution finished with.
+// This is a *PanicError for a panicked OperationFunction
+// or ErrShutdown for an execution rejected by the scheduler.
+func (e *execution) Err() error {
+	return e.state.Err()
+}
+
+// State returns the actual processing state of the execution.
+func (e *execution) State() ExecutionState {
+	return e.state.State()
 }
 
 func (e *execution) IsDone() bool {
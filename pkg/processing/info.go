@@ -0,0 +1,120 @@
+package processing
+
+// ElementInfo describes the actual state of a synchronization
+// element. Fields not applicable to an element are left empty.
+type ElementInfo struct {
+	Name string `json:"name"`
+	// Locked is set for locked mutexes. If the lock is passed
+	// to a waiting operation, this operation is already reported
+	// as holder, even if it is not yet running.
+	Locked  bool     `json:"locked,omitempty"`
+	Holders []string `json:"holders,omitempty"`
+	// Readers is the number of readers holding a RWMutex.
+	Readers int `json:"readers,omitempty"`
+	// Waiting is the number of operations waiting for the element.
+	Waiting int `json:"waiting"`
+	// Dependencies is the number of pending dependencies of a Trigger.
+	Dependencies int  `json:"dependencies,omitempty"`
+	Armed        bool `json:"armed,omitempty"`
+	Triggered    bool `json:"triggered,omitempty"`
+	// Size is the fill level of a Channel or the used
+	// units of a Semaphore.
+	Size     int  `json:"size,omitempty"`
+	Capacity int  `json:"capacity,omitempty"`
+	Closed   bool `json:"closed,omitempty"`
+}
+
+// Inspectable is implemented by synchronization elements
+// able to describe their actual state. Info does not wait for
+// an element to be released or triggered, but it synchronizes
+// with state changes in progress. For example, it waits for
+// the actions of a Trigger being executed and must therefore
+// not be called by a TriggerAction for the same trigger.
+type Inspectable interface {
+	Info() ElementInfo
+}
+
+var (
+	_ Inspectable = (Mutex)(nil)
+	_ Inspectable = (RWMutex)(nil)
+	_ Inspectable = (ReentrantMutex)(nil)
+	_ Inspectable = (Semaphore)(nil)
+	_ Inspectable = (Monitor)(nil)
+	_ Inspectable = (Trigger)(nil)
+	_ Inspectable = (Channel[int])(nil)
+)
+
+func (m *mutex) Info() ElementInfo {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	info := ElementInfo{
+		Name:    m.waiting.Name(),
+		Locked:  m.locked,
+		Waiting: m.waiting.Len(),
+	}
+	if m.holder != nil {
+		info.Holders = []string{m.holder.Name()}
+	}
+	return info
+}
+
+func (m *rwmutex) Info() ElementInfo {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	info := ElementInfo{
+		Name:    m.writers.Name(),
+		Locked:  m.locked,
+		Readers: m.rcnt,
+		Waiting: m.readers.Len() + m.writers.Len(),
+	}
+	if m.holder != nil {
+		info.Holders = []string{m.holder.Name()}
+	}
+	return info
+}
+
+func (m *reentrantMutex) Info() ElementInfo {
+	return m.lock.Info()
+}
+
+func (s *semaphore) Info() ElementInfo {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return ElementInfo{
+		Name:     s.waiting.Name(),
+		Waiting:  len(s.requests),
+		Size:     s.used,
+		Capacity: s.capacity,
+	}
+}
+
+func (m *monitor) Info() ElementInfo {
+	return m.lock.Info()
+}
+
+func (t *trigger) Info() ElementInfo {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return ElementInfo{
+		Name:         t.waiting.Name(),
+		Waiting:      t.waiting.Len(),
+		Dependencies: t.dependencies,
+		Armed:        t.armed,
+		Triggered:    t.isTriggered(),
+	}
+}
+
+func (c *channel[T]) Info() ElementInfo {
+	info := c.monitor.Info()
+	return ElementInfo{
+		Name:     info.Name,
+		Waiting:  info.Waiting + c.send.waiting.Len() + c.receive.waiting.Len(),
+		Size:     int(c.level.Load()),
+		Capacity: c.capacity,
+		Closed:   c.closed.Load(),
+	}
+}
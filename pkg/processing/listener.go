@@ -0,0 +1,58 @@
+package processing
+
+// SchedulerListener is informed about the state transitions of the
+// operations of a Scheduler. The queues passed to the callbacks are
+// the queues the operation is added to.
+// The callbacks are called synchronously, mostly under the lock
+// of the scheduler, therefore they must not block and must not
+// call methods of the scheduler or the operations, besides the
+// informational ones like Name and Priority. The same applies
+// to the logger set by WithLogger and the sink set by WithMetrics,
+// which are attached as listeners, too.
+type SchedulerListener interface {
+	// Started is called when an operation is started. The queue
+	// is the running or the ready queue of the scheduler.
+	Started(op Operation, q Queue)
+	// Blocked is called when an operation is blocked in a queue.
+	Blocked(op Operation, q Queue)
+	// Unblocked is called when a blocked operation is unblocked.
+	// The queue is the running or the ready queue of the scheduler.
+	Unblocked(op Operation, q Queue)
+	// Preempted is called when a running operation gives up its
+	// processor. next is the operation getting the processor, if any.
+	Preempted(op Operation, next Operation)
+	// Scheduled is called when a ready operation gets a processor.
+	Scheduled(op Operation)
+	// Finished is called with the final state of an operation,
+	// including operations never started, because they have been
+	// skipped or cancelled.
+	Finished(op Operation, state ExecutionState)
+}
+
+// SchedulerListenerBase is a SchedulerListener ignoring all
+// transitions. It can be embedded to implement only
+// selected callbacks.
+type SchedulerListenerBase struct{}
+
+var _ SchedulerListener = SchedulerListenerBase{}
+
+func (SchedulerListenerBase) Started(op Operation, q Queue)               {}
+func (SchedulerListenerBase) Blocked(op Operation, q Queue)               {}
+func (SchedulerListenerBase) Unblocked(op Operation, q Queue)             {}
+func (SchedulerListenerBase) Preempted(op Operation, next Operation)      {}
+func (SchedulerListenerBase) Scheduled(op Operation)                      {}
+func (SchedulerListenerBase) Finished(op Operation, state ExecutionState) {}
+
+// WithListener adds listeners informed about the state transitions
+// of the operations of the Scheduler.
+func WithListener(l ...SchedulerListener) Option {
+	return func(s *scheduler) {
+		s.listeners = append(s.listeners, l...)
+	}
+}
+
+func (s *scheduler) notify(f func(l SchedulerListener)) {
+	for _, l := range s.listeners {
+		f(l)
+	}
+}
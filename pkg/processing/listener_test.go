@@ -0,0 +1,77 @@
+package processing_test
+
+import (
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/processing/pkg/processing"
+)
+
+type recorder struct {
+	processing.SchedulerListenerBase
+	lock   sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(e string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *recorder) Events() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+func (r *recorder) Started(op processing.Operation, q processing.Queue) {
+	r.add("started " + op.Name() + " " + q.Name())
+}
+
+func (r *recorder) Blocked(op processing.Operation, q processing.Queue) {
+	r.add("blocked " + op.Name() + " " + q.Name())
+}
+
+func (r *recorder) Unblocked(op processing.Operation, q processing.Queue) {
+	r.add("unblocked " + op.Name() + " " + q.Name())
+}
+
+func (r *recorder) Scheduled(op processing.Operation) {
+	r.add("scheduled " + op.Name())
+}
+
+func (r *recorder) Finished(op processing.Operation, state processing.ExecutionState) {
+	r.add("finished " + op.Name() + " " + state.String())
+}
+
+var _ = Describe("scheduler listener", func() {
+	It("reports transitions", func() {
+		rec := &recorder{}
+		sched := processing.New(1, processing.WithListener(rec))
+		release := processing.NewTrigger("release")
+
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			release.Wait(op)
+		}, sched, "e1").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+		e2 := processing.NewExecution(func(op processing.Operation) {
+			release.Arm()
+			release.Trigger()
+		}, sched, "e2").Start()
+		processing.NewDependencyTrigger(nil, e1, e2).Wait(nil)
+
+		Expect(rec.Events()).To(Equal([]string{
+			"started execution:e1 running",
+			"blocked execution:e1 trigger:release",
+			"started execution:e2 running",
+			"unblocked execution:e1 ready",
+			"finished execution:e2 Done",
+			"scheduled execution:e1",
+			"finished execution:e1 Done",
+		}))
+	})
+})
@@ -0,0 +1,80 @@
+package processing
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+// WithLogger attaches a logger to the Scheduler, which is used to
+// emit structured events for the scheduling of operations.
+// Operations are identified by the key "operation" using the names
+// provided by ElementName.
+// The lifecycle of operations (start, done, skip) is logged with
+// verbosity 1, the scheduling decisions (block, unblock, preempt)
+// with verbosity 2. Panics of operation functions are logged as
+// errors.
+// The logger is called by a SchedulerListener and must obey
+// its restrictions.
+func WithLogger(log logr.Logger) Option {
+	return WithListener(&logListener{log: log, started: map[Operation]struct{}{}})
+}
+
+// logListener is the SchedulerListener logging the
+// scheduling events.
+type logListener struct {
+	SchedulerListenerBase
+	log logr.Logger
+
+	lock    sync.Mutex
+	started map[Operation]struct{} // to distinguish skipped operations
+}
+
+var _ SchedulerListener = (*logListener)(nil)
+
+func (l *logListener) Started(op Operation, q Queue) {
+	l.lock.Lock()
+	l.started[op] = struct{}{}
+	l.lock.Unlock()
+	l.log.V(1).Info("operation started", "operation", op.Name(), "queue", q.Name())
+}
+
+func (l *logListener) Blocked(op Operation, q Queue) {
+	l.log.V(2).Info("operation blocked", "operation", op.Name(), "queue", q.Name())
+}
+
+func (l *logListener) Unblocked(op Operation, q Queue) {
+	// called under the scheduler lock guarding blockedIn
+	l.log.V(2).Info("operation unblocked", "operation", op.Name(), "blockedIn", op.(State).blockedIn, "queue", q.Name())
+}
+
+func (l *logListener) Preempted(op Operation, next Operation) {
+	if next == nil {
+		l.log.V(2).Info("operation preempted", "operation", op.Name())
+	} else {
+		l.log.V(2).Info("operation preempted", "operation", op.Name(), "next", next.Name())
+	}
+}
+
+func (l *logListener) Finished(op Operation, state ExecutionState) {
+	l.lock.Lock()
+	_, started := l.started[op]
+	delete(l.started, op)
+	l.lock.Unlock()
+
+	msg := "operation finished"
+	if !started {
+		msg = "operation skipped"
+	}
+	err := op.(State).Err()
+	var perr *PanicError
+	if errors.As(err, &perr) {
+		l.log.Error(perr, "operation panicked", "operation", op.Name())
+	}
+	if err != nil {
+		l.log.V(1).Info(msg, "operation", op.Name(), "state", state.String(), "error", err.Error())
+	} else {
+		l.log.V(1).Info(msg, "operation", op.Name(), "state", state.String())
+	}
+}
@@ -0,0 +1,68 @@
+package processing_test
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/processing/pkg/processing"
+)
+
+var _ = Describe("logging", func() {
+	var lock sync.Mutex
+	var lines []string
+
+	log := funcr.New(func(prefix, args string) {
+		lock.Lock()
+		defer lock.Unlock()
+		lines = append(lines, args)
+	}, funcr.Options{Verbosity: 2})
+
+	BeforeEach(func() {
+		lines = nil
+	})
+
+	It("logs scheduling events", func() {
+		sched := processing.New(1, processing.WithLogger(log))
+		release := processing.NewTrigger("release")
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			release.Wait(op)
+		}, sched, "e1").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+		release.Arm()
+		release.Trigger()
+		e1.Wait(nil)
+
+		lock.Lock()
+		defer lock.Unlock()
+		Expect(lines).To(Equal([]string{
+			`"level"=1 "msg"="operation started" "operation"="execution:e1" "queue"="running"`,
+			`"level"=2 "msg"="operation blocked" "operation"="execution:e1" "queue"="trigger:release"`,
+			`"level"=2 "msg"="operation unblocked" "operation"="execution:e1" "blockedIn"="trigger:release" "queue"="running"`,
+			`"level"=1 "msg"="operation finished" "operation"="execution:e1" "state"="Done"`,
+		}))
+	})
+
+	It("logs skips and panics", func() {
+		sched := processing.New(1, processing.WithLogger(log))
+		t := processing.NewTask[int](func(op processing.Operation) (int, error) {
+			panic("failed")
+		}, sched, "t")
+		s := processing.NewTask[int](func(op processing.Operation) (int, error) {
+			return 0, nil
+		}, sched, "s")
+		s.DependsOn(t)
+		s.Start()
+		t.Start()
+		processing.NewDependencyTrigger(nil, t, s).Wait(nil)
+
+		lock.Lock()
+		defer lock.Unlock()
+		Expect(lines).To(ContainElement(HavePrefix(`"msg"="operation panicked" "error"="operation panicked: failed`)))
+		Expect(lines).To(ContainElement(fmt.Sprintf(`"level"=1 "msg"="operation skipped" "operation"="task:s" "state"="Skipped" "error"=%q`, s.Status().Error())))
+	})
+})
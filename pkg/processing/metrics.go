@@ -0,0 +1,57 @@
+package processing
+
+import (
+	"time"
+)
+
+// Metrics is a sink for scheduling events, which can be used
+// to collect metrics about the operations of a Scheduler.
+// The methods are called by a SchedulerListener and must obey
+// its restrictions.
+// The package github.com/mandelsoft/processing/pkg/processing/metrics
+// provides an implementation exporting the Prometheus text format.
+type Metrics interface {
+	// OperationStarted is called for every started operation.
+	OperationStarted()
+	// OperationFinished is called with the final state for every
+	// finished operation, including operations never started
+	// because they have been skipped or cancelled.
+	OperationFinished(state ExecutionState)
+	// OperationReady is called with the time an operation has
+	// spent in the ready queue before getting a processor.
+	OperationReady(wait time.Duration)
+	// OperationBlocked is called with the name of the queue and
+	// the duration an operation has been blocked, once the
+	// operation is unblocked.
+	OperationBlocked(queue string, d time.Duration)
+}
+
+// WithMetrics sets the sink for scheduling events.
+func WithMetrics(m Metrics) Option {
+	return WithListener(&metricsListener{metrics: m})
+}
+
+// metricsListener is the SchedulerListener passing the
+// scheduling events to a Metrics sink.
+type metricsListener struct {
+	SchedulerListenerBase
+	metrics Metrics
+}
+
+func (l *metricsListener) Started(op Operation, q Queue) {
+	l.metrics.OperationStarted()
+}
+
+func (l *metricsListener) Unblocked(op Operation, q Queue) {
+	// called under the scheduler lock guarding blockedIn and since
+	b := op.(State)
+	l.metrics.OperationBlocked(b.blockedIn, time.Since(b.since))
+}
+
+func (l *metricsListener) Scheduled(op Operation) {
+	l.metrics.OperationReady(op._queuedFor())
+}
+
+func (l *metricsListener) Finished(op Operation, state ExecutionState) {
+	l.metrics.OperationFinished(state)
+}
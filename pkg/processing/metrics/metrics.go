@@ -0,0 +1,179 @@
+// Package metrics provides an implementation of processing.Metrics
+// exporting the collected metrics in the Prometheus text exposition
+// format.
+//
+//	m := metrics.New("processing")
+//	sched := processing.New(4, processing.WithMetrics(m))
+//	m.Observe(sched)
+//	http.Handle("/metrics", m)
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mandelsoft/processing/pkg/processing"
+)
+
+type summary struct {
+	count uint64
+	sum   float64
+}
+
+func (s *summary) observe(d time.Duration) {
+	s.count++
+	s.sum += d.Seconds()
+}
+
+// Collector collects the scheduling events of a Scheduler.
+type Collector struct {
+	lock sync.Mutex
+
+	namespace string
+	scheduler processing.Scheduler
+
+	started   uint64
+	finished  map[processing.ExecutionState]uint64
+	readyWait summary
+	blocked   map[string]*summary
+}
+
+var (
+	_ processing.Metrics = (*Collector)(nil)
+	_ http.Handler       = (*Collector)(nil)
+)
+
+// New creates a Collector using the given namespace as
+// prefix for the metric names.
+func New(namespace string) *Collector {
+	return &Collector{
+		namespace: namespace,
+		finished:  map[processing.ExecutionState]uint64{},
+		blocked:   map[string]*summary{},
+	}
+}
+
+// Observe sets the scheduler used to provide the gauges for
+// the actual operations and the processor utilization.
+func (c *Collector) Observe(s processing.Scheduler) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.scheduler = s
+}
+
+func (c *Collector) OperationStarted() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.started++
+}
+
+func (c *Collector) OperationFinished(state processing.ExecutionState) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.finished[state]++
+}
+
+func (c *Collector) OperationReady(wait time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.readyWait.observe(wait)
+}
+
+func (c *Collector) OperationBlocked(queue string, d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	s := c.blocked[queue]
+	if s == nil {
+		s = &summary{}
+		c.blocked[queue] = s
+	}
+	s.observe(d)
+}
+
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Write(w)
+}
+
+// Write writes the metrics in the Prometheus text exposition format.
+func (c *Collector) Write(w io.Writer) error {
+	c.lock.Lock()
+	var b strings.Builder
+
+	c.header(&b, "operations_started_total", "counter", "Number of started operations.")
+	fmt.Fprintf(&b, "%s %d\n", c.name("operations_started_total"), c.started)
+
+	c.header(&b, "operations_finished_total", "counter", "Number of finished operations by final state.")
+	for _, s := range []processing.ExecutionState{processing.Done, processing.Failed, processing.Cancelled, processing.Skipped} {
+		fmt.Fprintf(&b, "%s{state=\"%s\"} %d\n", c.name("operations_finished_total"), strings.ToLower(s.String()), c.finished[s])
+	}
+
+	c.header(&b, "ready_wait_seconds", "summary", "Time operations spent in the ready queue.")
+	fmt.Fprintf(&b, "%s %g\n", c.name("ready_wait_seconds_sum"), c.readyWait.sum)
+	fmt.Fprintf(&b, "%s %d\n", c.name("ready_wait_seconds_count"), c.readyWait.count)
+
+	c.header(&b, "block_duration_seconds", "summary", "Time operations have been blocked by queue.")
+	var queues []string
+	for q := range c.blocked {
+		queues = append(queues, q)
+	}
+	sort.Strings(queues)
+	for _, q := range queues {
+		s := c.blocked[q]
+		fmt.Fprintf(&b, "%s{queue=\"%s\"} %g\n", c.name("block_duration_seconds_sum"), escape(q), s.sum)
+		fmt.Fprintf(&b, "%s{queue=\"%s\"} %d\n", c.name("block_duration_seconds_count"), escape(q), s.count)
+	}
+	sched := c.scheduler
+	c.lock.Unlock()
+
+	if sched != nil {
+		snap := sched.Snapshot()
+		c.header(&b, "operations", "gauge", "Number of actual operations by scheduling state.")
+		fmt.Fprintf(&b, "%s{state=\"running\"} %d\n", c.name("operations"), snap.Running)
+		fmt.Fprintf(&b, "%s{state=\"ready\"} %d\n", c.name("operations"), snap.Ready)
+		fmt.Fprintf(&b, "%s{state=\"blocked\"} %d\n", c.name("operations"), snap.Blocked)
+
+		c.header(&b, "processors", "gauge", "Limit for concurrently running operations.")
+		fmt.Fprintf(&b, "%s %d\n", c.name("processors"), snap.Limit)
+
+		c.header(&b, "processor_utilization", "gauge", "Ratio of processors used by running operations.")
+		// after decreasing the limit more operations may
+		// still be running until they are preempted.
+		utilization := 1.0
+		if snap.Running < snap.Limit {
+			utilization = float64(snap.Running) / float64(snap.Limit)
+		}
+		fmt.Fprintf(&b, "%s %g\n", c.name("processor_utilization"), utilization)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (c *Collector) name(n string) string {
+	if c.namespace == "" {
+		return n
+	}
+	return c.namespace + "_" + n
+}
+
+func (c *Collector) header(b *strings.Builder, n, typ, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n", c.name(n), help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", c.name(n), typ)
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(v string) string {
+	return escaper.Replace(v)
+}
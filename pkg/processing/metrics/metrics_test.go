@@ -0,0 +1,82 @@
+package metrics_test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/processing/pkg/processing"
+	"github.com/mandelsoft/processing/pkg/processing/metrics"
+)
+
+var _ = Describe("metrics", func() {
+	It("collects scheduling events", func() {
+		m := metrics.New("test")
+		sched := processing.New(1, processing.WithMetrics(m))
+		m.Observe(sched)
+
+		release := processing.NewTrigger("release")
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			release.Wait(op)
+		}, sched, "e1").Start()
+		e2 := processing.NewExecution(func(op processing.Operation) {
+			release.Wait(op)
+		}, sched, "e2").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(2))
+
+		t := processing.NewTask[int](func(op processing.Operation) (int, error) {
+			return 0, fmt.Errorf("failed")
+		}, sched, "t")
+		s := processing.NewTask[int](func(op processing.Operation) (int, error) {
+			return 0, nil
+		}, sched, "s")
+		s.DependsOn(t)
+		s.Start()
+		t.Start()
+
+		release.Arm()
+		release.Trigger()
+		processing.NewDependencyTrigger(nil, e1, e2, t, s).Wait(nil)
+
+		var b strings.Builder
+		Expect(m.Write(&b)).To(Succeed())
+		text := b.String()
+		Expect(text).To(ContainSubstring("# TYPE test_operations_started_total counter\ntest_operations_started_total 3\n"))
+		Expect(text).To(ContainSubstring(`test_operations_finished_total{state="done"} 2`))
+		Expect(text).To(ContainSubstring(`test_operations_finished_total{state="failed"} 1`))
+		Expect(text).To(ContainSubstring(`test_operations_finished_total{state="skipped"} 1`))
+		Expect(text).To(MatchRegexp(`\ntest_ready_wait_seconds_count [1-9]`))
+		Expect(text).To(ContainSubstring(`test_block_duration_seconds_count{queue="trigger:release"} 2`))
+		Expect(text).To(ContainSubstring(`test_operations{state="running"} 0`))
+		Expect(text).To(ContainSubstring("test_processors 1\n"))
+		Expect(text).To(ContainSubstring("test_processor_utilization 0\n"))
+	})
+
+	It("caps the processor utilization after decreasing the limit", func() {
+		m := metrics.New("test")
+		sched := processing.New(2, processing.WithMetrics(m))
+		m.Observe(sched)
+
+		release := make(chan struct{})
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			<-release
+		}, sched, "e1").Start()
+		e2 := processing.NewExecution(func(op processing.Operation) {
+			<-release
+		}, sched, "e2").Start()
+		Eventually(func() int { return sched.Snapshot().Running }, 5*time.Second).Should(Equal(2))
+		sched.SetLimit(1)
+
+		var b strings.Builder
+		Expect(m.Write(&b)).To(Succeed())
+		text := b.String()
+		Expect(text).To(ContainSubstring("test_processors 1\n"))
+		Expect(text).To(ContainSubstring("test_processor_utilization 1\n"))
+
+		close(release)
+		processing.NewDependencyTrigger(nil, e1, e2).Wait(nil)
+	})
+})
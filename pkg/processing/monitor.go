@@ -1,12 +1,28 @@
 package processing
 
+import (
+	"sync"
+	"time"
+)
+
 type Condition *condition
 
+// Monitor is a mutex for operations, which allows to wait
+// for conditions. The E variants of the methods return an error
+// if the wait is interrupted. Like for a regular wake-up, the
+// monitor is held again by the operation in this case, so a deferred
+// Unlock stays valid. The variants without error panic instead to stop
+// the operation.
 type Monitor interface {
 	Lock(Operation)
+	LockE(Operation) error
 	Wait(Condition)
+	WaitE(Condition) error
 	Notify(Condition)
+	NotifyE(Condition) error
 	Unlock()
+
+	Info() ElementInfo
 }
 
 type condition struct {
@@ -14,8 +30,14 @@ type condition struct {
 }
 
 func NewCondition(names ...string) Condition {
+	return NewConditionWithQueue(nil, names...)
+}
+
+// NewConditionWithQueue creates a Condition using the given
+// queuing policy for waiting operations.
+func NewConditionWithQueue(f QueueFactory, names ...string) Condition {
 	return &condition{
-		waiting: NewQueue(ElementName("condition", names...)),
+		waiting: queueFactory(f)(ElementName("condition", names...)),
 	}
 }
 
@@ -24,14 +46,18 @@ type monitor struct {
 }
 
 func NewMonitor(names ...string) Monitor {
-	return newMonitor("monitor", names...)
+	return NewMonitorWithQueue(nil, names...)
 }
 
-func newMonitor(typ string, names ...string) Monitor {
+// NewMonitorWithQueue creates a Monitor using the given
+// queuing policy for operations waiting for the monitor.
+func NewMonitorWithQueue(f QueueFactory, names ...string) Monitor {
+	return newMonitor(f, "monitor", names...)
+}
+
+func newMonitor(f QueueFactory, typ string, names ...string) *monitor {
 	return &monitor{
-		lock: &mutex{
-			waiting: NewQueue(ElementName(typ, names...)),
-		},
+		lock: newMutex(f, typ, names...),
 	}
 }
 
@@ -39,19 +65,65 @@ func (m *monitor) Lock(op Operation) {
 	m.lock.Lock(op)
 }
 
+func (m *monitor) LockE(op Operation) error {
+	return m.enter(op, time.Time{})
+}
+
+func (m *monitor) enter(op Operation, deadline time.Time) error {
+	return m.lock.acquire(op, deadline)
+}
+
+func (m *monitor) tryEnter(op Operation) bool {
+	return m.lock.TryLock(op)
+}
+
 func (m *monitor) Wait(c Condition) {
+	must(m.WaitE(c))
+}
+
+// WaitE waits for the condition. If the wait is interrupted
+// an error is returned after the monitor has been reacquired.
+func (m *monitor) WaitE(c Condition) error {
+	return m.await(c, time.Time{})
+}
+
+// await waits for the condition. The given additional locks
+// are released once the operation is queued for the condition.
+// If the wait is interrupted, the monitor is reacquired without
+// interruption before the error is returned.
+func (m *monitor) await(c Condition, deadline time.Time, locks ...sync.Locker) error {
 	m.lock.lock.Lock()
 
 	if m.lock.holder == nil {
 		m.lock.lock.Unlock()
+		for _, l := range locks {
+			l.Unlock()
+		}
 		panic("wait executed outside monitor")
 	}
 	holder := m.lock.holder
-	m.lock.holder.Block(c.waiting, m.lock.unlock)
-	m.lock.holder = holder
+	release := func() {
+		m.lock.unlock()
+		for _, l := range locks {
+			l.Unlock()
+		}
+	}
+	// the monitor is passed back by the notifying operation
+	err := holder._blockUntil(c.waiting, release, deadline)
+	if err != nil {
+		m.lock.relock(holder)
+	}
+	return err
 }
 
 func (m *monitor) Notify(c Condition) {
+	must(m.NotifyE(c))
+}
+
+// NotifyE notifies a waiting operation. If reacquiring the monitor
+// is interrupted, an error is returned after the monitor has been
+// reacquired without interruption.
+func (m *monitor) NotifyE(c Condition) error {
 	m.lock.lock.Lock()
 
 	if m.lock.holder == nil {
@@ -60,13 +132,48 @@ func (m *monitor) Notify(c Condition) {
 	}
 	holder := m.lock.holder
 
-	if n := c.waiting.Next(); n != nil {
+	if n := dequeue(c.waiting); n != nil {
+		m.lock.setHolder(n)
 		m.lock.lock.Unlock()
-		n.Unblock()    // pass monitor lock to unblocked wait
-		m.Lock(holder) // reacquire lock to continue
-	} else {
+		n.Unblock() // pass monitor lock to unblocked wait
+		// reacquire lock to continue
+		if err := m.LockE(holder); err != nil {
+			m.lock.relock(holder)
+			return err
+		}
+		return nil
+	}
+	m.lock.lock.Unlock()
+	return nil
+}
+
+// release unlocks the monitor. If there is an operation waiting
+// for the given condition, the monitor is passed to this operation.
+// In contrast to Notify followed by Unlock, the caller never blocks.
+func (m *monitor) release(c Condition) {
+	m.lock.lock.Lock()
+
+	if n := dequeue(c.waiting); n != nil {
+		m.lock.setHolder(n)
 		m.lock.lock.Unlock()
+		n.Unblock() // pass monitor lock to unblocked wait
+		return
+	}
+	m.lock.unlock()
+}
+
+// next removes the next operation waiting for the given condition
+// and passes the monitor to it. The caller holding the monitor
+// must unblock this operation.
+func (m *monitor) next(c Condition) Operation {
+	m.lock.lock.Lock()
+	defer m.lock.lock.Unlock()
+
+	n := dequeue(c.waiting)
+	if n != nil {
+		m.lock.setHolder(n)
 	}
+	return n
 }
 
 func (m *monitor) Unlock() {
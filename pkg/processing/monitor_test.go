@@ -1,6 +1,7 @@
 package processing_test
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -96,3 +97,61 @@ var _ = Describe("monitor", func() {
 		fmt.Printf("monitor done\n")
 	})
 })
+
+var _ = Describe("interrupted monitor", func() {
+	It("stops the waiting operation", func() {
+		sched := processing.New(1)
+		mon := processing.NewMonitor()
+		cond := processing.NewCondition()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		awaken := false
+		e := processing.NewExecutionWithContext(ctx, func(op processing.Operation) {
+			mon.Lock(op)
+			defer mon.Unlock()
+			mon.Wait(cond)
+			awaken = true
+		}, sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+
+		cancel()
+		e.Wait(nil)
+		Expect(awaken).To(BeFalse())
+		Expect(e.Err()).To(MatchError(context.Canceled))
+		Expect(e.State()).To(Equal(processing.Cancelled))
+		Expect(mon.Info().Locked).To(BeFalse())
+	})
+
+	It("reacquires the monitor held by another operation", func() {
+		sched := processing.New(2)
+		mon := processing.NewMonitor("m")
+		cond := processing.NewCondition()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var werr error
+		e1 := processing.NewExecutionWithContext(ctx, func(op processing.Operation) {
+			mon.Lock(op)
+			defer mon.Unlock()
+			werr = mon.WaitE(cond)
+		}, sched, "e1").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+
+		release := make(chan struct{})
+		e2 := processing.NewExecution(func(op processing.Operation) {
+			mon.Lock(op)
+			defer mon.Unlock()
+			<-release
+		}, sched, "e2").Start()
+		Eventually(mon.Info, 5*time.Second).Should(HaveField("Holders", []string{"execution:e2"}))
+
+		cancel()
+		Eventually(mon.Info, 5*time.Second).Should(HaveField("Waiting", 1))
+		Consistently(mon.Info, 100*time.Millisecond).Should(HaveField("Holders", []string{"execution:e2"}))
+
+		close(release)
+		processing.NewDependencyTrigger(nil, e1, e2).Wait(nil)
+		Expect(werr).To(MatchError(context.Canceled))
+		Expect(e2.State()).To(Equal(processing.Done))
+		Expect(mon.Info().Locked).To(BeFalse())
+	})
+})
@@ -2,12 +2,14 @@ package processing
 
 import (
 	"sync"
+	"time"
 )
 
 type Mutex = *mutex
 
 type mutex struct {
-	lock sync.Mutex
+	lock  sync.Mutex
+	hlock sync.Mutex // guards holder for the deadlock detection
 
 	waiting Queue
 	locked  bool
@@ -15,36 +17,145 @@ type mutex struct {
 }
 
 func NewMutex(names ...string) Mutex {
+	return NewMutexWithQueue(nil, names...)
+}
+
+// NewMutexWithQueue creates a Mutex using the given
+// queuing policy for waiting operations.
+func NewMutexWithQueue(f QueueFactory, names ...string) Mutex {
+	return newMutex(f, "mutex", names...)
+}
+
+func newMutex(f QueueFactory, typ string, names ...string) *mutex {
 	return &mutex{
-		waiting: NewQueue(ElementName("mutex", names...)),
+		waiting: queueFactory(f)(ElementName(typ, names...)),
 	}
 }
 
+// Lock acquires the mutex for the given operation.
+// If the operation is cancelled while waiting for the mutex,
+// Lock panics with the error to stop the operation. Use LockE
+// to handle this situation.
 func (m *mutex) Lock(o Operation) {
+	must(m.LockE(o))
+}
+
+// LockE acquires the mutex for the given operation.
+// If the wait is interrupted, the error is returned and
+// the mutex is not acquired.
+func (m *mutex) LockE(o Operation) error {
+	return m.acquire(o, time.Time{})
+}
+
+// LockTimeout acquires the mutex for the given operation.
+// If the mutex cannot be acquired in the given time,
+// ErrTimeout is returned.
+func (m *mutex) LockTimeout(o Operation, d time.Duration) error {
+	return m.acquire(o, time.Now().Add(d))
+}
+
+// TryLock tries to acquire the mutex for the given operation
+// without blocking. It reports whether the mutex could be acquired.
+func (m *mutex) TryLock(o Operation) bool {
+	if !m.lock.TryLock() {
+		return false
+	}
+	defer m.lock.Unlock()
+
+	if m.locked {
+		return false
+	}
+	m.setHolder(o)
+	m.locked = true
+	return true
+}
+
+func (m *mutex) acquire(o Operation, deadline time.Time) error {
 	m.lock.Lock()
 
-	for m.locked {
-		o.Block(m.waiting, m.lock.Unlock)
+	if m.locked {
+		o._setWaitFor(m.holders)
+		defer o._setWaitFor(nil)
+		// the mutex is passed by unlock
+		return o._blockUntil(m.waiting, m.lock.Unlock, deadline)
 	}
+	m.setHolder(o)
+	m.locked = true
+	m.lock.Unlock()
+	return nil
+}
+
+// setHolder sets the operation holding the mutex.
+// It must be called while holding the internal lock.
+func (m *mutex) setHolder(o Operation) {
+	m.hlock.Lock()
+	defer m.hlock.Unlock()
 	m.holder = o
+}
+
+// relock acquires the mutex for an operation, which
+// is expected to hold it, regardless of any interruption.
+func (m *mutex) relock(o Operation) {
+	m.lock.Lock()
+
+	if m.locked {
+		o._setWaitFor(m.holders)
+		defer o._setWaitFor(nil)
+		// the mutex is passed by unlock
+		o._blockUninterruptible(m.waiting, m.lock.Unlock)
+		return
+	}
+	m.setHolder(o)
 	m.locked = true
 	m.lock.Unlock()
 }
 
+// holders provides the operation holding the mutex.
+// It is used for the deadlock detection while holding
+// the scheduler lock and therefore only uses the leaf
+// lock guarding the holder.
+func (m *mutex) holders() []Operation {
+	m.hlock.Lock()
+	defer m.hlock.Unlock()
+
+	if m.holder == nil {
+		return nil
+	}
+	return []Operation{m.holder}
+}
+
+// Unlock releases the mutex. It does not verify, that the mutex
+// is held by the calling operation, use UnlockBy for this.
 func (m *mutex) Unlock() {
 	m.lock.Lock()
 	m.unlock()
 }
 
+// UnlockBy releases the mutex held by the given operation.
+// It panics, if the mutex is not held by this operation.
+func (m *mutex) UnlockBy(o Operation) {
+	m.lock.Lock()
+	if !m.locked || m.holder != o {
+		m.lock.Unlock()
+		panic("unlocking mutex not held by operation")
+	}
+	m.unlock()
+}
+
 func (m *mutex) unlock() {
 	if !m.locked {
 		panic("unlocking unlocked mutex")
 	}
-	m.holder = nil
-	m.locked = false
-	if n := m.waiting.Next(); n != nil {
-		go n.Unblock() // pass lock
-	} else {
+	if n := dequeue(m.waiting); n != nil {
+		// pass lock, the internal lock must not be kept
+		// until n is running, because the releasing operation
+		// may block on it while occupying the processor.
+		m.setHolder(n)
 		m.lock.Unlock()
+		go n.Unblock()
+		return
 	}
+	m.setHolder(nil)
+	m.locked = false
+	m.lock.Unlock()
 }
@@ -1,6 +1,7 @@
 package processing_test
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -110,3 +111,208 @@ var _ = Describe("locking", func() {
 		fmt.Printf("sequence done\n")
 	})
 })
+
+var _ = Describe("locking with timeout", func() {
+	var sched processing.Scheduler
+	var lock processing.Mutex
+
+	BeforeEach(func() {
+		sched = processing.New(1)
+		lock = processing.NewMutex()
+	})
+
+	It("times out", func() {
+		release := processing.NewTrigger()
+
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			lock.Lock(op)
+			release.Wait(op)
+			lock.Unlock()
+		}, sched, "holder").Start()
+
+		var lerr error
+		e2 := processing.NewExecution(func(op processing.Operation) {
+			lerr = lock.LockTimeout(op, 100*time.Millisecond)
+		}, sched, "waiter").Start()
+
+		Expect(e2.WaitE(nil)).To(Succeed())
+		Expect(lerr).To(Equal(processing.ErrTimeout))
+		Expect(sched.BlockedCount()).To(Equal(1))
+
+		release.Arm()
+		release.Trigger()
+		Expect(e1.WaitE(nil)).To(Succeed())
+		Expect(sched.BlockedCount()).To(Equal(0))
+		Expect(sched.ActiveCount()).To(Equal(0))
+	})
+
+	It("locks in time", func() {
+		var lerr error
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			lerr = lock.LockTimeout(op, 0)
+			lock.Unlock()
+		}, sched).Start()
+
+		Expect(e1.WaitE(nil)).To(Succeed())
+		Expect(lerr).To(Succeed())
+	})
+})
+
+var _ = Describe("try locking", func() {
+	It("does not block", func() {
+		sched := processing.New(1)
+		lock := processing.NewMutex()
+
+		var ok1, ok2, ok3 bool
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			ok1 = lock.TryLock(op)
+			ok2 = lock.TryLock(op)
+			lock.Unlock()
+			ok3 = lock.TryLock(op)
+			lock.Unlock()
+		}, sched).Start()
+
+		Expect(e1.WaitE(nil)).To(Succeed())
+		Expect(ok1).To(BeTrue())
+		Expect(ok2).To(BeFalse())
+		Expect(ok3).To(BeTrue())
+	})
+})
+
+var _ = Describe("verified unlocking", func() {
+	It("rejects unlock by other operations", func() {
+		sched := processing.New(1)
+		lock := processing.NewMutex()
+
+		var failure interface{}
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			lock.Lock(op)
+			func() {
+				defer func() { failure = recover() }()
+				lock.UnlockBy(nil)
+			}()
+			Expect(lock.Info().Locked).To(BeTrue())
+			lock.UnlockBy(op)
+		}, sched).Start()
+
+		Expect(e1.WaitE(nil)).To(Succeed())
+		Expect(failure).To(Equal("unlocking mutex not held by operation"))
+		Expect(e1.Err()).To(BeNil())
+		Expect(lock.Info().Locked).To(BeFalse())
+		Expect(func() { lock.UnlockBy(nil) }).To(PanicWith("unlocking mutex not held by operation"))
+	})
+})
+
+var _ = Describe("lock hand-off", func() {
+	It("passes the lock to a waiting operation", func() {
+		sched := processing.New(1)
+		lock := processing.NewMutex("handoff")
+		results := &LockResults{}
+		release := processing.NewTrigger()
+
+		var info processing.ElementInfo
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			lock.Lock(op)
+			results.Add(LOCK, "e1")
+			release.Wait(op)
+			lock.Unlock()
+			info = lock.Info()
+			lock.Lock(op)
+			results.Add(LOCK, "e1")
+			lock.Unlock()
+		}, sched, "e1").Start()
+		e2 := processing.NewExecution(func(op processing.Operation) {
+			lock.Lock(op)
+			results.Add(LOCK, "e2")
+			lock.Unlock()
+		}, sched, "e2").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(2))
+
+		release.Arm()
+		release.Trigger()
+		Expect(e1.WaitE(nil)).To(Succeed())
+		Expect(e2.WaitE(nil)).To(Succeed())
+		Expect(results.list).To(Equal([]string{
+			LOCK.R("e1"),
+			LOCK.R("e2"),
+			LOCK.R("e1"),
+		}))
+		Expect(info.Locked).To(BeTrue())
+		Expect(info.Holders).To(Equal([]string{"execution:e2"}))
+		Expect(lock.Info().Locked).To(BeFalse())
+	})
+})
+
+var _ = Describe("locking policy", func() {
+	It("wakes up waiting operations in LIFO order", func() {
+		sched := processing.New(4)
+		lock := processing.NewMutexWithQueue(processing.LIFOPolicy())
+		results := &LockResults{}
+
+		locked := processing.NewTrigger()
+		release := make(chan struct{})
+		e0 := processing.NewExecution(func(op processing.Operation) {
+			lock.Lock(op)
+			locked.Arm()
+			locked.Trigger()
+			<-release
+			lock.Unlock()
+		}, sched).Start()
+		locked.Wait(nil)
+
+		var execs []processing.Dependency
+		for _, name := range []string{"e1", "e2", "e3"} {
+			name := name
+			cnt := sched.BlockedCount()
+			execs = append(execs, processing.NewExecution(func(op processing.Operation) {
+				lock.Lock(op)
+				results.Add(LOCK, name)
+				lock.Unlock()
+			}, sched).Start())
+			Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(cnt + 1))
+		}
+		close(release)
+		processing.NewDependencyTrigger(nil, append(execs, e0)...).Wait(nil)
+		Expect(results.list).To(Equal([]string{
+			LOCK.R("e3"),
+			LOCK.R("e2"),
+			LOCK.R("e1"),
+		}))
+	})
+})
+
+var _ = Describe("interrupted locking", func() {
+	It("stops the operation", func() {
+		sched := processing.New(2)
+		lock := processing.NewMutex()
+		ctx, cancel := context.WithCancel(context.Background())
+		release := make(chan struct{})
+
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			lock.Lock(op)
+			<-release
+			lock.Unlock()
+		}, sched, "holder").Start()
+		Eventually(func() bool { return lock.Info().Locked }, 5*time.Second).Should(BeTrue())
+
+		entered := false
+		e2 := processing.NewExecutionWithContext(ctx, func(op processing.Operation) {
+			lock.Lock(op)
+			entered = true
+			lock.Unlock()
+		}, sched, "waiter").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+
+		cancel()
+		e2.Wait(nil)
+		Expect(entered).To(BeFalse())
+		Expect(e2.Err()).To(BeAssignableToTypeOf(&processing.PanicError{}))
+		Expect(e2.Err()).To(MatchError(context.Canceled))
+		Expect(e2.State()).To(Equal(processing.Cancelled))
+		Expect(lock.Info().Holders).To(Equal([]string{"execution:holder"}))
+
+		close(release)
+		e1.Wait(nil)
+		Expect(e1.State()).To(Equal(processing.Done))
+	})
+})
@@ -1,7 +1,10 @@
 package processing_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -142,3 +145,475 @@ var _ = Describe("simple processing", func() {
 	})
 
 })
+
+var _ = Describe("cancellation", func() {
+	var sched processing.Scheduler
+
+	BeforeEach(func() {
+		sched = processing.New(1)
+	})
+
+	It("interrupts blocked operations", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := processing.NewChannel[string](1)
+		trigger := processing.NewTrigger()
+
+		var rerr error
+		e1 := processing.NewExecutionWithContext(ctx, func(op processing.Operation) {
+			_, rerr = ch.Receive(op)
+		}, sched, "receiver").Start()
+		e2 := processing.NewExecutionWithContext(ctx, func(op processing.Operation) {
+			trigger.Wait(op)
+		}, sched, "waiter").Start()
+
+		sync := processing.NewDependencyTrigger(nil, e1, e2)
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(2))
+
+		cancel()
+		sync.Wait(nil)
+		Expect(rerr).To(Equal(context.Canceled))
+		Expect(trigger.IsTriggered()).To(BeFalse())
+		Expect(sched.BlockedCount()).To(Equal(0))
+		Expect(sched.ActiveCount()).To(Equal(0))
+	})
+
+	It("stops operations interrupted in Trigger.Wait and Execution.Wait", func() {
+		sched := processing.New(2)
+		ctx1, cancel1 := context.WithCancel(context.Background())
+		ctx2, cancel2 := context.WithCancel(context.Background())
+		trigger := processing.NewTrigger()
+
+		awaken := 0
+		e1 := processing.NewExecutionWithContext(ctx1, func(op processing.Operation) {
+			trigger.Wait(op)
+			awaken++
+		}, sched, "e1").Start()
+		e2 := processing.NewExecutionWithContext(ctx2, func(op processing.Operation) {
+			e1.Wait(op)
+			awaken++
+		}, sched, "e2").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(2))
+
+		cancel2()
+		e2.Wait(nil)
+		Expect(e2.State()).To(Equal(processing.Cancelled))
+		Expect(e2.Err()).To(MatchError(context.Canceled))
+
+		cancel1()
+		e1.Wait(nil)
+		Expect(e1.State()).To(Equal(processing.Cancelled))
+		Expect(e1.Err()).To(MatchError(context.Canceled))
+		Expect(awaken).To(Equal(0))
+	})
+})
+
+var _ = Describe("wake-up reason", func() {
+	var sched processing.Scheduler
+
+	BeforeEach(func() {
+		sched = processing.New(1)
+	})
+
+	It("passes the reason to the blocked operation", func() {
+		reason := fmt.Errorf("reason")
+		queue := processing.NewQueue("test")
+
+		var berr error
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			berr = op.BlockE(queue, nil)
+		}, sched).Start()
+
+		Eventually(queue.Len, 5*time.Second).Should(Equal(1))
+		queue.Next().UnblockE(reason)
+		e1.Wait(nil)
+		Expect(berr).To(BeIdenticalTo(reason))
+		Expect(sched.BlockedCount()).To(Equal(0))
+	})
+
+	It("reports interrupted mutex lock", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		mutex := processing.NewMutex()
+		locked := processing.NewTrigger()
+
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			mutex.Lock(op)
+			locked.Arm()
+			locked.Trigger()
+		}, sched)
+
+		var lerr error
+		e2 := processing.NewExecutionWithContext(ctx, func(op processing.Operation) {
+			locked.Wait(op)
+			lerr = mutex.LockE(op)
+		}, sched)
+
+		e2.Start()
+		e1.Start()
+		Expect(e1.WaitE(nil)).To(Succeed())
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+		cancel()
+		Expect(e2.WaitE(nil)).To(Succeed())
+		Expect(lerr).To(Equal(context.Canceled))
+	})
+})
+
+var _ = Describe("trigger with timeout", func() {
+	It("times out", func() {
+		sched := processing.New(1)
+		trigger := processing.NewTrigger()
+
+		var werr error
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			werr = trigger.WaitTimeout(op, 100*time.Millisecond)
+		}, sched).Start()
+
+		Expect(trigger.WaitTimeout(nil, 100*time.Millisecond)).To(Equal(processing.ErrTimeout))
+		Expect(e1.WaitE(nil)).To(Succeed())
+		Expect(werr).To(Equal(processing.ErrTimeout))
+		Expect(sched.BlockedCount()).To(Equal(0))
+	})
+})
+
+var _ = Describe("trigger polling", func() {
+	It("reports the triggered state", func() {
+		trigger := processing.NewTrigger()
+		Expect(trigger.Poll()).To(BeFalse())
+		trigger.Arm()
+		Expect(trigger.Poll()).To(BeFalse())
+		trigger.Trigger()
+		Expect(trigger.Poll()).To(BeTrue())
+	})
+})
+
+var _ = Describe("dependency trigger", func() {
+	It("depends on finished execution", func() {
+		sched := processing.New(1)
+		e1 := processing.NewExecution(func(op processing.Operation) {}, sched).Start()
+		Expect(e1.WaitE(nil)).To(Succeed())
+
+		triggered := make(chan struct{})
+		go func() {
+			processing.NewDependencyTrigger(nil, e1).Wait(nil)
+			close(triggered)
+		}()
+		Eventually(triggered, 5*time.Second).Should(BeClosed())
+	})
+})
+
+var _ = Describe("shutdown", func() {
+	var sched processing.Scheduler
+
+	BeforeEach(func() {
+		sched = processing.New(1)
+	})
+
+	It("waits for operations and rejects new ones", func() {
+		trigger := processing.NewTrigger()
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			trigger.Wait(op)
+		}, sched, "waiter").Start()
+
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			trigger.Arm()
+			trigger.Trigger()
+		}()
+		sched.Drain()
+		Expect(e1.IsDone()).To(BeTrue())
+		Expect(sched.IsClosed()).To(BeTrue())
+
+		e2 := processing.NewExecution(func(op processing.Operation) {}, sched)
+		Expect(e2.StartE()).To(Equal(processing.ErrShutdown))
+		Expect(e2.IsDone()).To(BeTrue())
+
+		e3 := processing.NewExecution(func(op processing.Operation) {}, sched).Start()
+		Expect(e3).NotTo(BeNil())
+		e3.Wait(nil)
+		Expect(e3.State()).To(Equal(processing.Cancelled))
+		Expect(e3.Err()).To(Equal(processing.ErrShutdown))
+		Expect(e3.Start()).To(BeNil())
+
+		t := processing.NewTask(task("t", NewStepper(&LockResults{})), sched)
+		t.Start()
+		_, err := t.Wait(nil)
+		Expect(err).To(Equal(processing.ErrShutdown))
+	})
+
+	It("reports blocked operations", func() {
+		trigger := processing.NewTrigger()
+		processing.NewExecution(func(op processing.Operation) {
+			trigger.Wait(op)
+		}, sched, "waiter").Start()
+
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		err := sched.Shutdown(ctx)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(err.Error()).To(ContainSubstring("execution:waiter"))
+
+		err = sched.Close()
+		Expect(err).To(MatchError(processing.ErrAborted))
+		Expect(err.Error()).To(Equal("operation aborted (aborted operations: execution:waiter)"))
+		Expect(sched.BlockedCount()).To(Equal(0))
+		Expect(trigger.IsTriggered()).To(BeFalse())
+	})
+
+	It("aborts blocked operations", func() {
+		lock := processing.NewMutex()
+		var lerr error
+		processing.NewExecution(func(op processing.Operation) {
+			lock.Lock(op)
+			lerr = lock.LockE(op)
+		}, sched, "locker").Start()
+
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+		Expect(sched.BlockedOperations()).To(Equal([]string{"execution:locker"}))
+		Expect(sched.Close()).To(MatchError(processing.ErrAborted))
+		Expect(lerr).To(Equal(processing.ErrAborted))
+	})
+
+	It("cancels aborted operations", func() {
+		sched := processing.New(2)
+		lock := processing.NewMutex()
+		trigger := processing.NewTrigger()
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			lock.Lock(op)
+			if lock.LockE(op) != nil {
+				return
+			}
+		}, sched, "locker").Start()
+		e2 := processing.NewExecution(func(op processing.Operation) {
+			trigger.Wait(op)
+		}, sched, "waiter").Start()
+
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(2))
+		Expect(sched.Close()).To(MatchError("operation aborted (aborted operations: execution:locker, execution:waiter)"))
+		Expect(e1.State()).To(Equal(processing.Cancelled))
+		Expect(e1.Err()).To(Equal(processing.ErrAborted))
+		Expect(e2.State()).To(Equal(processing.Cancelled))
+		Expect(e2.Err()).To(MatchError(processing.ErrAborted))
+	})
+})
+
+var _ = Describe("priorities", func() {
+	var results *LockResults
+
+	BeforeEach(func() {
+		results = &LockResults{}
+	})
+
+	record := func(name string) processing.OperationFunction {
+		return func(op processing.Operation) {
+			results.Add(START, name)
+		}
+	}
+
+	occupy := func(sched processing.Scheduler) chan struct{} {
+		release := make(chan struct{})
+		processing.NewExecution(func(op processing.Operation) {
+			<-release
+		}, sched).Start()
+		return release
+	}
+
+	It("schedules higher priorities first", func() {
+		sched := processing.New(1)
+		release := occupy(sched)
+
+		e1 := processing.NewExecution(record("e1"), sched)
+		e2 := processing.NewExecution(record("e2"), sched)
+		e2.SetPriority(5)
+		e3 := processing.NewExecution(record("e3"), sched)
+		e3.SetPriority(1)
+		e1.Start()
+		e2.Start()
+		e3.Start()
+		e1.SetPriority(10)
+		Expect(sched.ReadyCount()).To(Equal(3))
+
+		close(release)
+		processing.NewDependencyTrigger(nil, e1, e2, e3).Wait(nil)
+		Expect(results.list).To(Equal([]string{
+			START.R("e1"),
+			START.R("e2"),
+			START.R("e3"),
+		}))
+	})
+
+	It("avoids starvation by aging", func() {
+		sched := processing.New(1, processing.WithAging(10*time.Millisecond))
+		release := occupy(sched)
+
+		e1 := processing.NewExecution(record("e1"), sched).Start()
+		time.Sleep(200 * time.Millisecond)
+		e2 := processing.NewExecution(record("e2"), sched)
+		e2.SetPriority(5)
+		e2.Start()
+
+		close(release)
+		processing.NewDependencyTrigger(nil, e1, e2).Wait(nil)
+		Expect(results.list).To(Equal([]string{
+			START.R("e1"),
+			START.R("e2"),
+		}))
+	})
+})
+
+var _ = Describe("ready queue policy", func() {
+	It("shares processors among groups", func() {
+		results := &LockResults{}
+		group := func(op processing.Operation) string {
+			return strings.Split(op.Name(), ":")[1]
+		}
+		sched := processing.New(1, processing.WithReadyQueue(processing.FairSharePolicy(group)))
+
+		release := make(chan struct{})
+		processing.NewExecution(func(op processing.Operation) {
+			<-release
+		}, sched, "occupy").Start()
+
+		var execs []processing.Dependency
+		for _, n := range [][]string{{"a", "1"}, {"a", "2"}, {"a", "3"}, {"b", "1"}, {"b", "2"}} {
+			name := strings.Join(n, "")
+			execs = append(execs, processing.NewExecution(func(op processing.Operation) {
+				results.Add(START, name)
+			}, sched, n...).Start())
+		}
+		close(release)
+		processing.NewDependencyTrigger(nil, execs...).Wait(nil)
+		Expect(results.list).To(Equal([]string{
+			START.R("a1"),
+			START.R("b1"),
+			START.R("a2"),
+			START.R("b2"),
+			START.R("a3"),
+		}))
+	})
+})
+
+var _ = Describe("processor limit", func() {
+	It("increases the limit", func() {
+		sched := processing.New(1)
+		release := make(chan struct{})
+		e0 := processing.NewExecution(func(op processing.Operation) {
+			<-release
+		}, sched).Start()
+
+		started := make(chan struct{}, 2)
+		e1 := processing.NewExecution(func(op processing.Operation) { started <- struct{}{} }, sched).Start()
+		e2 := processing.NewExecution(func(op processing.Operation) { started <- struct{}{} }, sched).Start()
+		Expect(sched.ReadyCount()).To(Equal(2))
+
+		sched.SetLimit(3)
+		Expect(sched.Limit()).To(Equal(3))
+		Eventually(started, 5*time.Second).Should(Receive())
+		Eventually(started, 5*time.Second).Should(Receive())
+		close(release)
+		for _, e := range []processing.Execution{e0, e1, e2} {
+			e.Wait(nil)
+		}
+	})
+
+	It("decreases the limit", func() {
+		sched := processing.New(2)
+		release1 := make(chan struct{})
+		release2 := make(chan struct{})
+		preempted := make(chan struct{})
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			<-release1
+			op.Preempt()
+			close(preempted)
+		}, sched).Start()
+		e2 := processing.NewExecution(func(op processing.Operation) {
+			<-release2
+		}, sched).Start()
+
+		sched.SetLimit(1)
+		e3 := processing.NewExecution(func(op processing.Operation) {}, sched).Start()
+		Expect(sched.ReadyCount()).To(Equal(1))
+		Expect(sched.RunningCount()).To(Equal(2))
+
+		close(release1)
+		Eventually(sched.RunningCount, 5*time.Second).Should(Equal(1))
+		Expect(sched.ReadyCount()).To(Equal(2))
+		Consistently(preempted).ShouldNot(BeClosed())
+
+		close(release2)
+		for _, e := range []processing.Execution{e1, e2, e3} {
+			e.Wait(nil)
+		}
+		Expect(sched.ActiveCount()).To(Equal(0))
+	})
+})
+
+var _ = Describe("panics", func() {
+	It("recovers panicking operations", func() {
+		sched := processing.New(1)
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			panic("failed")
+		}, sched).Start()
+
+		Expect(e1.WaitE(nil)).To(Succeed())
+		var perr *processing.PanicError
+		Expect(errors.As(e1.Err(), &perr)).To(BeTrue())
+		Expect(perr.Value).To(Equal("failed"))
+		Expect(string(perr.Stack)).To(ContainSubstring("processing_test.go"))
+		Expect(sched.ActiveCount()).To(Equal(0))
+
+		e2 := processing.NewExecution(func(op processing.Operation) {}, sched).Start()
+		Expect(e2.WaitE(nil)).To(Succeed())
+		Expect(e2.Err()).To(BeNil())
+	})
+})
+
+var _ = Describe("execution state", func() {
+	It("reports the processing state", func() {
+		sched := processing.New(1)
+		trigger := processing.NewTrigger()
+		release := make(chan struct{})
+
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			<-release
+			trigger.Wait(op)
+		}, sched)
+		e2 := processing.NewExecution(func(op processing.Operation) {
+			panic("failed")
+		}, sched)
+		Expect(e1.State()).To(Equal(processing.Pending))
+
+		e1.Start()
+		e2.Start()
+		Expect(e1.State()).To(Equal(processing.Running))
+		Expect(e2.State()).To(Equal(processing.Ready))
+
+		close(release)
+		Eventually(e1.State, 5*time.Second).Should(Equal(processing.Blocked))
+		Expect(e2.WaitE(nil)).To(Succeed())
+		Expect(e2.State()).To(Equal(processing.Failed))
+
+		trigger.Arm()
+		trigger.Trigger()
+		Expect(e1.WaitE(nil)).To(Succeed())
+		Expect(e1.State()).To(Equal(processing.Done))
+		Expect(e1.State().IsTerminal()).To(BeTrue())
+		Expect(e1.State().String()).To(Equal("Done"))
+	})
+
+	It("reports cancelled executions", func() {
+		sched := processing.New(1)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		e1 := processing.NewExecutionWithContext(ctx, func(op processing.Operation) {}, sched).Start()
+		Expect(e1.WaitE(nil)).To(Succeed())
+		Expect(e1.State()).To(Equal(processing.Cancelled))
+
+		sched.Drain()
+		e2 := processing.NewExecution(func(op processing.Operation) {}, sched)
+		Expect(e2.StartE()).To(Equal(processing.ErrShutdown))
+		Expect(e2.State()).To(Equal(processing.Cancelled))
+	})
+})
@@ -2,8 +2,15 @@ package processing
 
 import (
 	"sync"
+	"time"
 )
 
+// Queue is used to keep track of operations waiting for
+// some event. The order operations are served by Next is
+// defined by the queuing policy of the implementation.
+// Implementations only keep the list of queued operations,
+// the state of dequeued operations is maintained by the
+// scheduler and the synchronization primitives.
 type Queue interface {
 	Name() string
 	Add(b Operation)
@@ -12,16 +19,73 @@ type Queue interface {
 	Next() Operation
 }
 
+// dequeue removes the next operation from the given queue
+// and records that it is no longer queued.
+func dequeue(q Queue) Operation {
+	n := q.Next()
+	if n != nil {
+		n._removedFromQueue(q)
+	}
+	return n
+}
+
+// QueueFactory creates a Queue with a given name.
+// It is used to configure the queuing policy for the scheduler
+// and the synchronization primitives.
+type QueueFactory func(name string) Queue
+
+// FIFOPolicy serves operations in the order they have been queued.
+// This is the default policy.
+func FIFOPolicy() QueueFactory {
+	return NewQueue
+}
+
+// LIFOPolicy serves the latest queued operation first.
+func LIFOPolicy() QueueFactory {
+	return NewLIFOQueue
+}
+
+// PriorityPolicy serves operations with higher priority first,
+// see NewPriorityQueue.
+func PriorityPolicy(aging time.Duration) QueueFactory {
+	return func(name string) Queue {
+		return NewPriorityQueue(name, aging)
+	}
+}
+
+// FairSharePolicy serves operations of different groups
+// in a round-robin manner, see NewFairShareQueue.
+func FairSharePolicy(key func(Operation) string) QueueFactory {
+	return func(name string) Queue {
+		return NewFairShareQueue(name, key)
+	}
+}
+
+func queueFactory(f QueueFactory) QueueFactory {
+	if f == nil {
+		return NewQueue
+	}
+	return f
+}
+
 type queue struct {
 	lock sync.Mutex
 	name string
+	lifo bool
 	list []Operation
 }
 
+// NewQueue creates a FIFO queue.
 func NewQueue(name string) Queue {
 	return &queue{name: name}
 }
 
+// NewLIFOQueue creates a queue serving the latest
+// queued operation first.
+func NewLIFOQueue(name string) Queue {
+	return &queue{name: name, lifo: true}
+}
+
 func (q *queue) Name() string {
 	return q.name
 }
@@ -44,9 +108,14 @@ func (q *queue) Next() Operation {
 	defer q.lock.Unlock()
 
 	if len(q.list) > 0 {
-		r := q.list[0]
-		q.list = q.list[1:]
-		r._removedFromQueue(q)
+		var r Operation
+		if q.lifo {
+			r = q.list[len(q.list)-1]
+			q.list = q.list[:len(q.list)-1]
+		} else {
+			r = q.list[0]
+			q.list = q.list[1:]
+		}
 		return r
 	}
 	return nil
@@ -67,3 +136,185 @@ func (q *queue) Remove(b Operation) bool {
 	}
 	return false
 }
+
+////////////////////////////////////////////////////////////////////////////////
+
+type priorityQueue struct {
+	lock  sync.Mutex
+	name  string
+	aging time.Duration
+	list  []queueEntry
+}
+
+type queueEntry struct {
+	op    Operation
+	since time.Time
+}
+
+// NewPriorityQueue creates a Queue serving operations with a higher
+// priority first. Operations with the same priority are served
+// in FIFO order. If aging is positive, the effective priority of
+// a queued operation is increased by one for every elapsed
+// aging interval to avoid starvation.
+func NewPriorityQueue(name string, aging time.Duration) Queue {
+	return &priorityQueue{name: name, aging: aging}
+}
+
+func (q *priorityQueue) Name() string {
+	return q.name
+}
+
+func (q *priorityQueue) Add(b Operation) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.list = append(q.list, queueEntry{b, time.Now()})
+}
+
+func (q *priorityQueue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.list)
+}
+
+func (q *priorityQueue) Next() Operation {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if len(q.list) == 0 {
+		return nil
+	}
+	now := time.Now()
+	index := 0
+	prio := q.priority(q.list[0], now)
+	for i, e := range q.list[1:] {
+		if p := q.priority(e, now); p > prio {
+			index = i + 1
+			prio = p
+		}
+	}
+	r := q.list[index].op
+	q.list = append(q.list[:index], q.list[index+1:]...)
+	return r
+}
+
+func (q *priorityQueue) priority(e queueEntry, now time.Time) int {
+	p := e.op.Priority()
+	if q.aging > 0 {
+		p += int(now.Sub(e.since) / q.aging)
+	}
+	return p
+}
+
+func (q *priorityQueue) Remove(b Operation) bool {
+	if q == nil {
+		return false
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for i, e := range q.list {
+		if e.op == b {
+			q.list = append(q.list[:i], q.list[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type fairShareQueue struct {
+	lock   sync.Mutex
+	name   string
+	key    func(Operation) string
+	groups map[string][]Operation
+	order  []string
+	size   int
+}
+
+// NewFairShareQueue creates a Queue distributing the wake-ups
+// among groups of operations in a round-robin manner. Operations
+// of the same group are served in FIFO order. The group of
+// an operation is determined by the given key function, which
+// must provide a stable result for an operation. By default,
+// the operation name is used.
+func NewFairShareQueue(name string, key func(Operation) string) Queue {
+	if key == nil {
+		key = Operation.Name
+	}
+	return &fairShareQueue{name: name, key: key, groups: map[string][]Operation{}}
+}
+
+func (q *fairShareQueue) Name() string {
+	return q.name
+}
+
+func (q *fairShareQueue) Add(b Operation) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	k := q.key(b)
+	if len(q.groups[k]) == 0 {
+		q.order = append(q.order, k)
+	}
+	q.groups[k] = append(q.groups[k], b)
+	q.size++
+}
+
+func (q *fairShareQueue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.size
+}
+
+func (q *fairShareQueue) Next() Operation {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if len(q.order) == 0 {
+		return nil
+	}
+	k := q.order[0]
+	q.order = q.order[1:]
+	list := q.groups[k]
+	r := list[0]
+	if len(list) > 1 {
+		q.groups[k] = list[1:]
+		q.order = append(q.order, k)
+	} else {
+		delete(q.groups, k)
+	}
+	q.size--
+	return r
+}
+
+func (q *fairShareQueue) Remove(b Operation) bool {
+	if q == nil {
+		return false
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	k := q.key(b)
+	list := q.groups[k]
+	for i, e := range list {
+		if e == b {
+			list = append(list[:i], list[i+1:]...)
+			if len(list) > 0 {
+				q.groups[k] = list
+			} else {
+				delete(q.groups, k)
+				for j, o := range q.order {
+					if o == k {
+						q.order = append(q.order[:j], q.order[j+1:]...)
+						break
+					}
+				}
+			}
+			q.size--
+			return true
+		}
+	}
+	return false
+}
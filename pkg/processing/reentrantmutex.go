@@ -0,0 +1,91 @@
+package processing
+
+// ReentrantMutex is a Mutex, which can be locked multiple times
+// by the same operation. It must be unlocked by the holding
+// operation as many times as it has been locked, before
+// it is passed to another operation.
+type ReentrantMutex = *reentrantMutex
+
+type reentrantMutex struct {
+	lock  *mutex
+	count int
+}
+
+func NewReentrantMutex(names ...string) ReentrantMutex {
+	return &reentrantMutex{
+		lock: newMutex(nil, "reentrantmutex", names...),
+	}
+}
+
+// Lock acquires the mutex for the given operation or increments
+// the lock count, if the operation already holds the mutex.
+// If the wait is interrupted, Lock panics with the error to stop
+// the operation. Use LockE to handle this situation.
+func (m *reentrantMutex) Lock(o Operation) {
+	must(m.LockE(o))
+}
+
+// LockE acquires the mutex for the given operation or increments
+// the lock count, if the operation already holds the mutex.
+// If the wait is interrupted, the error is returned and the
+// mutex is not acquired.
+func (m *reentrantMutex) LockE(o Operation) error {
+	if m.enter(o) {
+		return nil
+	}
+	if err := m.lock.LockE(o); err != nil {
+		return err
+	}
+	m.count = 1
+	return nil
+}
+
+// TryLock tries to acquire the mutex without blocking.
+func (m *reentrantMutex) TryLock(o Operation) bool {
+	if m.enter(o) {
+		return true
+	}
+	if !m.lock.TryLock(o) {
+		return false
+	}
+	m.count = 1
+	return true
+}
+
+// enter increments the lock count, if the mutex is held by the operation.
+func (m *reentrantMutex) enter(o Operation) bool {
+	m.lock.lock.Lock()
+	defer m.lock.lock.Unlock()
+
+	if m.lock.locked && m.lock.holder == o {
+		m.count++
+		return true
+	}
+	return false
+}
+
+// IsHeldBy reports whether the mutex is held by the given operation.
+func (m *reentrantMutex) IsHeldBy(o Operation) bool {
+	m.lock.lock.Lock()
+	defer m.lock.lock.Unlock()
+
+	return m.lock.locked && m.lock.holder == o
+}
+
+// Unlock decrements the lock count of the holding operation and
+// releases the mutex once the count drops to zero.
+// It panics, if the mutex is not held by the given operation.
+func (m *reentrantMutex) Unlock(o Operation) {
+	m.lock.lock.Lock()
+
+	if !m.lock.locked || m.lock.holder != o {
+		m.lock.lock.Unlock()
+		panic("unlocking reentrant mutex not held by operation")
+	}
+	m.count--
+	if m.count > 0 {
+		m.lock.lock.Unlock()
+		return
+	}
+	m.lock.unlock()
+}
@@ -0,0 +1,89 @@
+package processing_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/processing/pkg/processing"
+)
+
+var _ = Describe("reentrant mutex", func() {
+	var sched processing.Scheduler
+	var results *LockResults
+	var lock processing.ReentrantMutex
+
+	BeforeEach(func() {
+		sched = processing.New(2)
+		results = &LockResults{}
+		lock = processing.NewReentrantMutex()
+	})
+
+	It("handles nested locks", func() {
+		release := processing.NewTrigger()
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			lock.Lock(op)
+			lock.Lock(op)
+			results.Add(LOCK, "e1")
+			release.Wait(op)
+			lock.Unlock(op)
+			Expect(lock.IsHeldBy(op)).To(BeTrue())
+			results.Add(UNLOCK, "e1")
+			lock.Unlock(op)
+		}, sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+
+		e2 := processing.NewExecution(func(op processing.Operation) {
+			lock.Lock(op)
+			results.Add(LOCK, "e2")
+			lock.Unlock(op)
+		}, sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(2))
+
+		release.Arm()
+		release.Trigger()
+		processing.NewDependencyTrigger(nil, e1, e2).Wait(nil)
+		Expect(results.list).To(Equal([]string{
+			LOCK.R("e1"),
+			UNLOCK.R("e1"),
+			LOCK.R("e2"),
+		}))
+	})
+
+	It("rejects unlock by other operations", func() {
+		var failure interface{}
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			lock.Lock(op)
+			func() {
+				defer func() { failure = recover() }()
+				lock.Unlock(nil)
+			}()
+			lock.Unlock(op)
+		}, sched).Start()
+
+		Expect(e1.WaitE(nil)).To(Succeed())
+		Expect(failure).To(Equal("unlocking reentrant mutex not held by operation"))
+		Expect(e1.Err()).To(BeNil())
+	})
+
+	It("stops operations interrupted in Lock", func() {
+		Expect(lock.TryLock(nil)).To(BeTrue())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		e := processing.NewExecutionWithContext(ctx, func(op processing.Operation) {
+			lock.Lock(op)
+			results.Add(LOCK, "e")
+			lock.Unlock(op)
+		}, sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+
+		cancel()
+		e.Wait(nil)
+		Expect(results.list).To(BeEmpty())
+		Expect(e.Err()).To(MatchError(context.Canceled))
+		Expect(e.State()).To(Equal(processing.Cancelled))
+		Expect(lock.IsHeldBy(nil)).To(BeTrue())
+	})
+})
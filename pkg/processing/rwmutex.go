@@ -0,0 +1,193 @@
+package processing
+
+import (
+	"sync"
+)
+
+// RWMutex is a reader/writer mutual exclusion lock for operations.
+// The lock can be held by an arbitrary number of readers or a single
+// writer. Blocked readers and writers release their processor.
+// With writer preference (the default) new readers are blocked
+// as long as a writer is waiting, and waiting readers are
+// admitted after a writer releases the lock. This way neither
+// writers nor readers starve. Without writer preference readers
+// are never blocked by waiting writers.
+type RWMutex = *rwmutex
+
+type rwmutex struct {
+	lock  sync.Mutex
+	hlock sync.Mutex // guards holder for the deadlock detection
+
+	preferWriters bool
+	readers       Queue
+	writers       Queue
+	rcnt          int
+	locked        bool
+	holder        Operation
+}
+
+// NewRWMutex creates a RWMutex with writer preference.
+func NewRWMutex(names ...string) RWMutex {
+	return NewRWMutexWithPreference(true, names...)
+}
+
+// NewRWMutexWithPreference creates a RWMutex with or without
+// writer preference.
+func NewRWMutexWithPreference(preferWriters bool, names ...string) RWMutex {
+	name := ElementName("rwmutex", names...)
+	return &rwmutex{
+		preferWriters: preferWriters,
+		readers:       NewQueue(name + ":readers"),
+		writers:       NewQueue(name + ":writers"),
+	}
+}
+
+// RLock acquires the read lock. If the wait is interrupted,
+// RLock panics with the error to stop the operation. Use RLockE
+// to handle this situation.
+func (m *rwmutex) RLock(o Operation) {
+	must(m.RLockE(o))
+}
+
+// RLockE acquires the read lock. If the wait is interrupted,
+// the error is returned and the lock is not acquired.
+func (m *rwmutex) RLockE(o Operation) error {
+	m.lock.Lock()
+
+	if !m.locked && !(m.preferWriters && m.writers.Len() > 0) {
+		m.rcnt++
+		m.lock.Unlock()
+		return nil
+	}
+	return m.wait(o, m.readers)
+}
+
+// TryRLock tries to acquire the read lock without blocking.
+func (m *rwmutex) TryRLock(o Operation) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.locked || (m.preferWriters && m.writers.Len() > 0) {
+		return false
+	}
+	m.rcnt++
+	return true
+}
+
+func (m *rwmutex) RUnlock() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.rcnt == 0 {
+		panic("runlocking unlocked rwmutex")
+	}
+	m.rcnt--
+	m.grant(false)
+}
+
+// Lock acquires the write lock. If the wait is interrupted,
+// Lock panics with the error to stop the operation. Use LockE
+// to handle this situation.
+func (m *rwmutex) Lock(o Operation) {
+	must(m.LockE(o))
+}
+
+// LockE acquires the write lock. If the wait is interrupted,
+// the error is returned and the lock is not acquired.
+func (m *rwmutex) LockE(o Operation) error {
+	m.lock.Lock()
+
+	if !m.locked && m.rcnt == 0 {
+		m.locked = true
+		m.setHolder(o)
+		m.lock.Unlock()
+		return nil
+	}
+	return m.wait(o, m.writers)
+}
+
+// TryLock tries to acquire the write lock without blocking.
+func (m *rwmutex) TryLock(o Operation) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.locked || m.rcnt != 0 {
+		return false
+	}
+	m.locked = true
+	m.setHolder(o)
+	return true
+}
+
+func (m *rwmutex) Unlock() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !m.locked {
+		panic("unlocking unlocked rwmutex")
+	}
+	m.locked = false
+	m.setHolder(nil)
+	m.grant(true)
+}
+
+// wait blocks the operation in the given queue until the lock
+// is granted by a releasing operation.
+func (m *rwmutex) wait(o Operation, q Queue) error {
+	o._setWaitFor(m.holders)
+	defer o._setWaitFor(nil)
+
+	err := o.BlockE(q, m.lock.Unlock)
+	if err != nil {
+		// the operation is not waiting anymore, this might
+		// enable other waiting operations.
+		m.lock.Lock()
+		m.grant(false)
+		m.lock.Unlock()
+	}
+	return err
+}
+
+// setHolder sets the operation holding the write lock.
+// It must be called while holding the internal lock.
+func (m *rwmutex) setHolder(o Operation) {
+	m.hlock.Lock()
+	defer m.hlock.Unlock()
+	m.holder = o
+}
+
+// holders provides the operation holding the write lock.
+// Readers are not tracked. It is used for the deadlock
+// detection while holding the scheduler lock and therefore
+// only uses the leaf lock guarding the holder.
+func (m *rwmutex) holders() []Operation {
+	m.hlock.Lock()
+	defer m.hlock.Unlock()
+
+	if m.holder == nil {
+		return nil
+	}
+	return []Operation{m.holder}
+}
+
+// grant passes the lock to waiting operations.
+// If readersFirst is set, waiting readers are admitted
+// even if writers are waiting.
+func (m *rwmutex) grant(readersFirst bool) {
+	if m.locked {
+		return
+	}
+	if readersFirst || !m.preferWriters || m.writers.Len() == 0 {
+		for n := dequeue(m.readers); n != nil; n = dequeue(m.readers) {
+			m.rcnt++
+			n.Unblock()
+		}
+	}
+	if m.rcnt == 0 {
+		if n := dequeue(m.writers); n != nil {
+			m.locked = true
+			m.setHolder(n)
+			n.Unblock()
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package processing_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/processing/pkg/processing"
+)
+
+var _ = Describe("rwmutex", func() {
+	var sched processing.Scheduler
+	var results *LockResults
+
+	BeforeEach(func() {
+		sched = processing.New(1)
+		results = &LockResults{}
+	})
+
+	reader := func(name string, lock processing.RWMutex, wait processing.Trigger) processing.OperationFunction {
+		return func(op processing.Operation) {
+			lock.RLock(op)
+			results.Add(LOCK, name)
+			if wait != nil {
+				wait.Wait(op)
+			}
+			lock.RUnlock()
+		}
+	}
+
+	writer := func(name string, lock processing.RWMutex) processing.OperationFunction {
+		return func(op processing.Operation) {
+			lock.Lock(op)
+			results.Add(LOCK, name)
+			lock.Unlock()
+		}
+	}
+
+	run := func(lock processing.RWMutex) {
+		release := processing.NewTrigger()
+		r1 := processing.NewExecution(reader("r1", lock, release), sched).Start()
+		r2 := processing.NewExecution(reader("r2", lock, release), sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(2))
+
+		w := processing.NewExecution(writer("w", lock), sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(3))
+		r3 := processing.NewExecution(reader("r3", lock, nil), sched).Start()
+		Eventually(results.Len, 5*time.Second).Should(BeNumerically(">=", 2))
+
+		release.Arm()
+		release.Trigger()
+		processing.NewDependencyTrigger(nil, r1, r2, r3, w).Wait(nil)
+		Expect(sched.ActiveCount()).To(Equal(0))
+	}
+
+	It("prefers writers", func() {
+		run(processing.NewRWMutex())
+		Expect(results.list).To(Equal([]string{
+			LOCK.R("r1"),
+			LOCK.R("r2"),
+			LOCK.R("w"),
+			LOCK.R("r3"),
+		}))
+	})
+
+	It("prefers readers", func() {
+		run(processing.NewRWMutexWithPreference(false))
+		Expect(results.list).To(Equal([]string{
+			LOCK.R("r1"),
+			LOCK.R("r2"),
+			LOCK.R("r3"),
+			LOCK.R("w"),
+		}))
+	})
+
+	It("admits readers after an interrupted writer", func() {
+		lock := processing.NewRWMutex()
+		release := processing.NewTrigger()
+		r1 := processing.NewExecution(reader("r1", lock, release), sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var lerr error
+		w := processing.NewExecutionWithContext(ctx, func(op processing.Operation) {
+			lerr = lock.LockE(op)
+		}, sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(2))
+		r2 := processing.NewExecution(reader("r2", lock, nil), sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(3))
+
+		cancel()
+		processing.NewDependencyTrigger(nil, r2, w).Wait(nil)
+		Expect(lerr).To(Equal(context.Canceled))
+		Expect(results.list).To(Equal([]string{
+			LOCK.R("r1"),
+			LOCK.R("r2"),
+		}))
+
+		release.Arm()
+		release.Trigger()
+		Expect(r1.WaitE(nil)).To(Succeed())
+		Expect(lock.TryLock(nil)).To(BeTrue())
+	})
+
+	It("stops operations interrupted in Lock", func() {
+		lock := processing.NewRWMutex()
+		release := processing.NewTrigger()
+		r1 := processing.NewExecution(reader("r1", lock, release), sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		w := processing.NewExecutionWithContext(ctx, writer("w", lock), sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(2))
+
+		cancel()
+		w.Wait(nil)
+		Expect(w.Err()).To(MatchError(context.Canceled))
+		Expect(w.State()).To(Equal(processing.Cancelled))
+		Expect(lock.Info().Readers).To(Equal(1))
+
+		release.Arm()
+		release.Trigger()
+		Expect(r1.WaitE(nil)).To(Succeed())
+		Expect(results.list).To(Equal([]string{
+			LOCK.R("r1"),
+		}))
+		Expect(lock.Info().Readers).To(Equal(0))
+	})
+})
@@ -1,7 +1,18 @@
 package processing
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+)
+
+var (
+	ErrTimeout  = fmt.Errorf("timeout exceeded")
+	ErrShutdown = fmt.Errorf("scheduler shut down")
+	ErrAborted  = fmt.Errorf("operation aborted")
 )
 
 // Scheduler is able to handle the execution of operations in parallel.
@@ -13,6 +24,11 @@ import (
 // The scheduler handles this by observing the executions blocked
 // on dedicated synchronization primitives supported by this
 // package.
+// Operations ready to run are scheduled according to their
+// priority, see Execution.SetPriority.
+// A scheduler can be shut down. Hereby, it stops accepting
+// new executions and waits for the started operations
+// to finish.
 type Scheduler = *scheduler
 
 type scheduler struct {
@@ -24,15 +40,189 @@ type scheduler struct {
 	ready   Queue
 	blocked Queue
 	bcnt    int
+	aging   time.Duration
+	policy  QueueFactory
+
+	operations map[State]struct{}
+	idle       chan struct{}
+	closed     bool
+	abort      error
+
+	detectDeadlocks bool
+	deadlocks       DeadlockHandler
+	failDeadlocks   bool
+
+	listeners []SchedulerListener
+
+	stallHandler StallHandler
+	stalled      *Stall
+	stallch      chan struct{}
 }
 
-func New(n int) Scheduler {
-	return &scheduler{
+// Option is an optional configuration for a Scheduler.
+type Option func(s *scheduler)
+
+// WithAging enables aging for ready operations. The effective
+// priority of a ready operation is increased by one for every
+// elapsed interval to avoid starvation of low priority operations.
+// Aging is a feature of the default priority based policy. It is
+// ignored, if another policy is configured with WithReadyQueue.
+// In this case use PriorityPolicy with the aging interval.
+func WithAging(interval time.Duration) Option {
+	return func(s *scheduler) {
+		s.aging = interval
+	}
+}
+
+// WithReadyQueue configures the queuing policy used to select
+// the next ready operation to run. It replaces the default
+// priority based policy, so an interval configured with WithAging
+// is ignored. Only the ready queue is affected, the running and
+// blocked queues are used for bookkeeping and always keep the
+// queuing order.
+func WithReadyQueue(f QueueFactory) Option {
+	return func(s *scheduler) {
+		s.policy = f
+	}
+}
+
+func New(n int, options ...Option) Scheduler {
+	s := &scheduler{
 		num_processors: n,
 
-		running: NewQueue("running"),
-		ready:   NewQueue("ready"),
-		blocked: NewQueue("blocked"),
+		operations: map[State]struct{}{},
+	}
+	for _, o := range options {
+		o(s)
+	}
+	if s.policy == nil {
+		s.policy = PriorityPolicy(s.aging)
+	}
+	s.running = NewQueue("running")
+	s.ready = s.policy("ready")
+	s.blocked = NewQueue("blocked")
+	return s
+}
+
+// IsClosed reports whether the scheduler has been shut down.
+func (s *scheduler) IsClosed() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.closed
+}
+
+// BlockedOperations returns the names of all operations
+// actually blocked by a synchronization primitive.
+func (s *scheduler) BlockedOperations() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var names []string
+	for b := range s.operations {
+		if b._blockingQueue() != nil {
+			names = append(names, b.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Shutdown stops accepting new executions and waits until all
+// started operations are finished. If the context is done before,
+// an error listing the still blocked operations is returned.
+func (s *scheduler) Shutdown(ctx context.Context) error {
+	s.lock.Lock()
+	s.closed = true
+	idle := s._idle()
+	s.lock.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		names := s.BlockedOperations()
+		if len(names) == 0 {
+			return fmt.Errorf("shutdown incomplete: %w", ctx.Err())
+		}
+		return fmt.Errorf("shutdown incomplete: %w (blocked operations: %s)", ctx.Err(), strings.Join(names, ", "))
+	}
+}
+
+// Drain stops accepting new executions and waits until all
+// started operations are finished.
+func (s *scheduler) Drain() {
+	s.Shutdown(context.Background())
+}
+
+// Close aborts all started operations and shuts down the scheduler.
+// Blocked operations are woken up with ErrAborted, and
+// further blocking attempts fail with this error.
+// Close returns once all operations are finished. If blocked
+// operations have been aborted, an error listing them is returned.
+func (s *scheduler) Close() error {
+	var names []string
+
+	s.lock.Lock()
+	s.closed = true
+	s.abort = ErrAborted
+	for b := range s.operations {
+		if s._interrupt(b, ErrAborted) {
+			names = append(names, b.Name())
+		}
+	}
+	idle := s._idle()
+	s.lock.Unlock()
+
+	<-idle
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return fmt.Errorf("%w (aborted operations: %s)", ErrAborted, strings.Join(names, ", "))
+}
+
+// _idle provides a channel closed once all operations are finished.
+func (s *scheduler) _idle() <-chan struct{} {
+	if s.idle == nil {
+		s.idle = make(chan struct{})
+		if len(s.operations) == 0 {
+			close(s.idle)
+		}
+	}
+	return s.idle
+}
+
+// Limit returns the actual limit for concurrently running operations.
+func (s *scheduler) Limit() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.num_processors
+}
+
+// SetLimit changes the limit for concurrently running operations.
+// Values below one are treated as one.
+// If the limit is increased, ready operations are started
+// immediately. If it is decreased, running operations are not
+// stopped, but their processor is taken away as soon as they block,
+// preempt or finish.
+func (s *scheduler) SetLimit(n int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if n < 1 {
+		n = 1
+	}
+	s.num_processors = n
+	for s.active_processors < s.num_processors {
+		r := dequeue(s.ready)
+		if r == nil {
+			break
+		}
+		s.active_processors++
+		s._run(r)
+		r._unblock()
 	}
 }
 
@@ -71,90 +261,272 @@ func (s *scheduler) WaitingCount() int {
 	return s.bcnt - s.blocked.Len()
 }
 
-func (s *scheduler) new(self interface{}, typ string, names ...string) State {
+func (s *scheduler) new(ctx context.Context, self interface{}, typ string, names ...string) State {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	return &state{
+		id:        ids.Add(1),
 		self:      self,
 		name:      ElementName(typ, names...),
+		ctx:       ctx,
 		scheduler: s,
 		done:      NewArmedTrigger(nil),
+		stop:      make(chan struct{}),
 	}
 }
 
-func (s *scheduler) start(b State, f func(o Operation)) {
+func (s *scheduler) start(b State, f func(o Operation)) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	if s.closed {
+		return ErrShutdown
+	}
+	s.operations[b] = struct{}{}
+	s._resolveStall()
+	b._started()
 	if s.active_processors < s.num_processors {
 		s.active_processors++
-		s.running.Add(b)
+		b._addToQueue(s.running, false)
 	} else {
 		b._block()
-		s.ready.Add(b)
+		b._addToQueue(s.ready, false)
+	}
+	q := b._queue()
+	s.notify(func(l SchedulerListener) { l.Started(b, q) })
+	if cancel := b.ctx.Done(); cancel != nil {
+		go s.watch(b, cancel)
 	}
-	go func() {
-		b.blocker.Lock()
-		f(b)
+	go s.run(b, f)
+	return nil
+}
+
+// run executes the operation function. A panic of the function
+// is recovered and recorded as failure of the operation.
+func (s *scheduler) run(b State, f func(o Operation)) {
+	b.blocker.Lock()
+	defer func() {
+		if r := recover(); r != nil {
+			b._setErr(newPanicError(r))
+		}
 		s.done(b)
 	}()
+	f(b)
+}
+
+// watch interrupts a blocked operation once its
+// context is cancelled.
+func (s *scheduler) watch(b State, cancel <-chan struct{}) {
+	select {
+	case <-cancel:
+		s.interrupt(b, b.ctx.Err())
+	case <-b.stop:
+	}
 }
 
 func (s *scheduler) done(b State) {
+	b._finish()
+	s.finished(b, b.State())
 	s.lock.Lock()
-	s.running.Remove(b)
+	if s.running.Remove(b) {
+		b._removedFromQueue(s.running)
+	}
 	s._schedule()
 	s.lock.Unlock()
+	close(b.stop)
 	b.done.Trigger()
+
+	// the scheduler becomes idle only after the operation is done
+	s.lock.Lock()
+	delete(s.operations, b)
+	if len(s.operations) == 0 && s.idle != nil {
+		close(s.idle)
+		s.idle = nil
+	}
+	stall := s._checkStall()
+	s.lock.Unlock()
+	s.reportStall(stall)
+}
+
+// _run moves a ready operation to the running queue.
+func (s *scheduler) _run(r Operation) {
+	// notify before the time spent in the ready queue is reset
+	s.notify(func(l SchedulerListener) { l.Scheduled(r) })
+	r._addToQueue(s.running, false)
 }
 
+// finished reports the final state of an operation.
+func (s *scheduler) finished(b State, state ExecutionState) {
+	s.notify(func(l SchedulerListener) { l.Finished(b, state) })
+}
+
+// _schedule passes the processor of a running operation to the next
+// ready operation. If there is none, or the processor limit has been
+// decreased, the processor is released.
 func (s *scheduler) _schedule() {
-	if r := s.ready.Next(); r != nil {
-		r._addToQueue(s.running, false)
-		r._unblock()
-	} else {
-		s.active_processors--
+	if s.active_processors <= s.num_processors {
+		if r := dequeue(s.ready); r != nil {
+			s._run(r)
+			r._unblock()
+			return
+		}
 	}
+	s.active_processors--
+}
+
+func (s *scheduler) block(b State, q Queue, r ReleaseFunction, deadline time.Time) error {
+	return s.suspend(b, q, r, deadline, true)
 }
 
-func (s *scheduler) block(b State, q Queue, r ReleaseFunction) {
+// blockUninterruptible blocks the operation until it is unblocked
+// regularly. Neither a cancellation, nor aborting the scheduler or the
+// deadlock detection wakes it up. It is used to reacquire locks
+// an interrupted operation is expected to hold.
+func (s *scheduler) blockUninterruptible(b State, q Queue, r ReleaseFunction) {
+	s.suspend(b, q, r, time.Time{}, false)
+}
+
+func (s *scheduler) suspend(b State, q Queue, r ReleaseFunction, deadline time.Time, interruptible bool) error {
 	s.lock.Lock()
 
+	var err error
+	if interruptible {
+		err = b.ctx.Err()
+		if err == nil {
+			err = s.abort
+		}
+		if err == nil && !deadline.IsZero() && !time.Now().Before(deadline) {
+			err = ErrTimeout
+		}
+	}
+	if err != nil {
+		if r != nil {
+			r()
+		}
+		s.lock.Unlock()
+		b._noteAbort(err)
+		return err
+	}
 	if q == nil {
 		q = s.blocked
 	}
+	b.blocks++
+	b.timed = !deadline.IsZero()
+	b.uninterruptible = !interruptible
+	b.since = time.Now()
+	b.blockedIn = q.Name()
 	b._addToQueue(q, true)
 	if r != nil {
 		r()
 	}
 	s.bcnt++
+	s.notify(func(l SchedulerListener) { l.Blocked(b, q) })
 	s._schedule()
+	stall := s._checkStall()
+
+	var timer *time.Timer
+	if !deadline.IsZero() {
+		seq := b.blocks
+		timer = time.AfterFunc(time.Until(deadline), func() { s.timeout(b, seq) })
+	}
 	s.lock.Unlock()
 
+	s.reportStall(stall)
+	if s.detectDeadlocks {
+		s.detectDeadlock(b)
+	}
 	b._block()
+	if timer != nil {
+		timer.Stop()
+	}
+	err = b._getWakeup()
+	b._noteAbort(err)
+	return err
+}
+
+func (s *scheduler) unblock(b State, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// the operation might have been taken by a direct call of Queue.Next
+	if q := b._blockingQueue(); q != nil {
+		q.Remove(b)
+		b._removedFromQueue(q)
+	}
+	b._setWakeup(err)
+	s._unblock(b)
+}
+
+// interrupt removes a blocked operation from the queue it is waiting in
+// and wakes it up with the given error. If the operation is not blocked
+// or has already been dequeued for a regular wake-up, nothing happens.
+func (s *scheduler) interrupt(b State, err error) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s._interrupt(b, err)
 }
 
-func (s *scheduler) unblock(b State) {
+// timeout interrupts the blocked operation with ErrTimeout,
+// if it is still blocked by the block with the given sequence number.
+func (s *scheduler) timeout(b State, seq uint64) bool {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	if b.blocks != seq {
+		return false
+	}
+	return s._interrupt(b, ErrTimeout)
+}
+
+func (s *scheduler) _interrupt(b State, err error) bool {
+	if b.uninterruptible {
+		return false
+	}
+	q := b._blockingQueue()
+	if q == nil || !q.Remove(b) {
+		return false
+	}
+	b._removedFromQueue(q)
+	b._setWakeup(err)
+	s._unblock(b)
+	return true
+}
+
+func (s *scheduler) _unblock(b State) {
 	s.bcnt--
+	s._resolveStall()
 	if s.active_processors < s.num_processors {
 		s.active_processors++
 		b._addToQueue(s.running, false)
+		s.notify(func(l SchedulerListener) { l.Unblocked(b, s.running) })
 		b._unblock()
 	} else {
 		b._addToQueue(s.ready, false)
+		s.notify(func(l SchedulerListener) { l.Unblocked(b, s.ready) })
 	}
 }
 
 func (s *scheduler) preempt(b State) {
 	s.lock.Lock()
 
-	if r := s.ready.Next(); r != nil {
+	if s.active_processors > s.num_processors {
+		// processor limit decreased, give up processor
+		b._addToQueue(s.ready, false)
+		s.notify(func(l SchedulerListener) { l.Preempted(b, nil) })
+		s.active_processors--
+		s.lock.Unlock()
+		b._block()
+		return
+	}
+	if r := dequeue(s.ready); r != nil {
 		b._addToQueue(s.ready, false)
-		r._addToQueue(s.running, false)
+		s._run(r)
+		s.notify(func(l SchedulerListener) { l.Preempted(b, r) })
 		r._unblock()
 		s.lock.Unlock()
 		b._block()
@@ -0,0 +1,253 @@
+package processing
+
+import (
+	"sync"
+)
+
+// SelectCase is a case for Select. It is created with
+// SendCase, ReceiveCase or TriggerCase.
+type SelectCase interface {
+	// poll executes the case, if it is ready. An error
+	// for a non-ready case interrupts the Select.
+	poll(op Operation) (bool, error)
+	watchers() *watchers
+	// offer offers the case to be executed by another operation
+	// while the Select is waiting. It reports false, if the case
+	// might be executed by polling again.
+	offer(s *selector, index int) bool
+	// withdraw withdraws an offer.
+	withdraw(s *selector)
+}
+
+// Select waits until one of the given cases is ready and executes it.
+// Like Go's select statement it waits for several Channel or Trigger
+// operations at once. While waiting, the operation releases its
+// processor. The cases are checked in the given order and the first
+// ready case is executed. Select returns the index of the executed case
+// and its error, for example ErrClosed for a closed Channel.
+// If the wait is interrupted, the index -1 and the error is returned.
+// While waiting, a send case for an unbuffered Channel can be taken by
+// a receiver, even if it executes a Select, too.
+func Select(op Operation, cases ...SelectCase) (int, error) {
+	sel := &selector{op: op, waiting: NewQueue(ElementName("select")), chosen: -1}
+	for _, c := range cases {
+		c.watchers().add(sel)
+	}
+	defer func() {
+		for _, c := range cases {
+			c.withdraw(sel)
+			c.watchers().remove(sel)
+		}
+	}()
+
+	for {
+		sel.lock.Lock()
+		sel.signaled = false
+		sel.lock.Unlock()
+
+		for i, c := range cases {
+			ok, err := c.poll(op)
+			if ok {
+				return i, err
+			}
+			if err != nil {
+				return -1, err
+			}
+		}
+
+		sel.lock.Lock()
+		if sel.signaled {
+			// state changed while polling
+			sel.lock.Unlock()
+			continue
+		}
+		sel.offering = true
+		sel.lock.Unlock()
+
+		offered := true
+		for i, c := range cases {
+			if !c.offer(sel, i) {
+				offered = false
+			}
+		}
+
+		var err error
+		sel.lock.Lock()
+		if offered && !sel.signaled && sel.chosen < 0 {
+			err = op.BlockE(sel.waiting, sel.lock.Unlock)
+			sel.lock.Lock()
+		}
+		sel.offering = false
+		chosen := sel.chosen
+		sel.lock.Unlock()
+
+		if chosen >= 0 {
+			// an offered case has been taken
+			return chosen, nil
+		}
+		if err != nil {
+			return -1, err
+		}
+	}
+}
+
+// SendCase is a Select case sending the given message to a Channel.
+func SendCase[T any](c Channel[T], t T) SelectCase {
+	return &sendCase[T]{c.(*channel[T]), t}
+}
+
+// ReceiveCase is a Select case receiving a message from a Channel.
+// The message is stored in the given variable, if it is not nil.
+func ReceiveCase[T any](c Channel[T], t *T) SelectCase {
+	return &receiveCase[T]{c.(*channel[T]), t}
+}
+
+// TriggerCase is a Select case waiting for a Trigger.
+func TriggerCase(t Trigger) SelectCase {
+	return &triggerCase{t.(*trigger)}
+}
+
+type sendCase[T any] struct {
+	channel *channel[T]
+	msg     T
+}
+
+func (c *sendCase[T]) poll(op Operation) (bool, error) {
+	return c.channel.pollSend(op, c.msg)
+}
+
+func (c *sendCase[T]) watchers() *watchers {
+	return &c.channel.watchers
+}
+
+func (c *sendCase[T]) offer(s *selector, index int) bool {
+	return c.channel.offer(s, index, c.msg)
+}
+
+func (c *sendCase[T]) withdraw(s *selector) {
+	c.channel.withdraw(s)
+}
+
+type receiveCase[T any] struct {
+	channel *channel[T]
+	msg     *T
+}
+
+func (c *receiveCase[T]) poll(op Operation) (bool, error) {
+	t, ok, err := c.channel.pollReceive(op)
+	if ok && err == nil && c.msg != nil {
+		*c.msg = t
+	}
+	return ok, err
+}
+
+func (c *receiveCase[T]) watchers() *watchers {
+	return &c.channel.watchers
+}
+
+func (c *receiveCase[T]) offer(s *selector, index int) bool {
+	return true
+}
+
+func (c *receiveCase[T]) withdraw(s *selector) {
+}
+
+type triggerCase struct {
+	trigger *trigger
+}
+
+func (c *triggerCase) poll(op Operation) (bool, error) {
+	return c.trigger.Poll(), nil
+}
+
+func (c *triggerCase) watchers() *watchers {
+	return &c.trigger.watchers
+}
+
+func (c *triggerCase) offer(s *selector, index int) bool {
+	return true
+}
+
+func (c *triggerCase) withdraw(s *selector) {
+}
+
+// selector is the waiting state of an operation executing a Select.
+type selector struct {
+	lock     sync.Mutex
+	op       Operation
+	waiting  Queue
+	signaled bool
+	offering bool // offered cases may be taken
+	chosen   int  // index of the taken case
+}
+
+// wake signals a state change of a watched element and
+// unblocks the operation, if it is waiting.
+func (s *selector) wake() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.signaled = true
+	if s.waiting.Remove(s.op) {
+		s.op._removedFromQueue(s.waiting)
+		s.op.Unblock()
+	}
+}
+
+// claim takes the offered case with the given index and
+// unblocks the operation. It fails, if the selector is not
+// waiting, or another case has already been taken.
+func (s *selector) claim(index int) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.offering || s.chosen >= 0 {
+		return false
+	}
+	s.chosen = index
+	if s.waiting.Remove(s.op) {
+		s.op._removedFromQueue(s.waiting)
+		s.op.Unblock()
+	}
+	return true
+}
+
+// watchers is a set of selectors watching an element.
+type watchers struct {
+	lock      sync.Mutex
+	selectors map[*selector]struct{}
+}
+
+func (w *watchers) add(s *selector) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.selectors == nil {
+		w.selectors = map[*selector]struct{}{}
+	}
+	w.selectors[s] = struct{}{}
+}
+
+func (w *watchers) remove(s *selector) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	delete(w.selectors, s)
+}
+
+// signal wakes all watching selectors.
+func (w *watchers) signal() {
+	w.signalOthers(nil)
+}
+
+// signalOthers wakes all watching selectors except the given one.
+func (w *watchers) signalOthers(sel *selector) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for s := range w.selectors {
+		if s != sel {
+			s.wake()
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package processing_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/processing/pkg/processing"
+)
+
+var _ = Describe("select", func() {
+	var sched processing.Scheduler
+
+	BeforeEach(func() {
+		sched = processing.New(1)
+	})
+
+	It("selects the first ready case", func() {
+		c1 := processing.NewChannel[int](1, "c1")
+		c2 := processing.NewChannel[int](1, "c2")
+		c2.TrySend(nil, 2)
+
+		var v int
+		var index int
+		var err error
+		e := processing.NewExecution(func(op processing.Operation) {
+			index, err = processing.Select(op, processing.ReceiveCase(c1, &v), processing.ReceiveCase(c2, &v))
+		}, sched).Start()
+		e.Wait(nil)
+		Expect(err).To(Succeed())
+		Expect(index).To(Equal(1))
+		Expect(v).To(Equal(2))
+	})
+
+	It("waits for channels and triggers", func() {
+		c1 := processing.NewChannel[int](1, "c1")
+		c2 := processing.NewChannel[int](1, "c2")
+		stop := processing.NewTrigger("stop")
+
+		var received []int
+		receiver := processing.NewExecution(func(op processing.Operation) {
+			for {
+				var v int
+				i, err := processing.Select(op,
+					processing.ReceiveCase(c1, &v),
+					processing.ReceiveCase(c2, &v),
+					processing.TriggerCase(stop),
+				)
+				Expect(err).To(Succeed())
+				if i == 2 {
+					return
+				}
+				received = append(received, i*10+v)
+			}
+		}, sched, "receiver").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+		Expect(sched.BlockedOperations()).To(Equal([]string{"execution:receiver"}))
+
+		sender := processing.NewExecution(func(op processing.Operation) {
+			c2.Send(op, 1)
+			c1.Send(op, 2)
+			c2.Send(op, 3)
+			Expect(processing.Select(op, processing.SendCase(c1, 4))).To(Equal(0))
+			stop.Arm()
+			stop.Trigger()
+		}, sched, "sender").Start()
+
+		processing.NewDependencyTrigger(nil, receiver, sender).Wait(nil)
+		Expect(received).To(ConsistOf(11, 2, 13, 4))
+	})
+
+	It("waits for sending", func() {
+		c := processing.NewChannel[int](1, "c")
+		c.TrySend(nil, 1)
+
+		var index int
+		sender := processing.NewExecution(func(op processing.Operation) {
+			index, _ = processing.Select(op, processing.SendCase(c, 2))
+		}, sched, "sender").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+
+		var values []int
+		receiver := processing.NewExecution(func(op processing.Operation) {
+			v, _ := c.Receive(op)
+			values = append(values, v)
+			v, _ = c.Receive(op)
+			values = append(values, v)
+		}, sched, "receiver").Start()
+
+		processing.NewDependencyTrigger(nil, receiver, sender).Wait(nil)
+		Expect(index).To(Equal(0))
+		Expect(values).To(Equal([]int{1, 2}))
+	})
+
+	It("matches selects on both ends of an unbuffered channel", func() {
+		c := processing.NewChannel[int](0, "c")
+		stop := processing.NewTrigger("stop")
+
+		var indices []int
+		sender := processing.NewExecution(func(op processing.Operation) {
+			for i := 1; i <= 3; i++ {
+				index, err := processing.Select(op, processing.TriggerCase(stop), processing.SendCase(c, i))
+				Expect(err).To(Succeed())
+				indices = append(indices, index)
+			}
+		}, sched, "sender").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+
+		var values []int
+		receiver := processing.NewExecution(func(op processing.Operation) {
+			for i := 1; i <= 3; i++ {
+				var v int
+				index, err := processing.Select(op, processing.TriggerCase(stop), processing.ReceiveCase(c, &v))
+				Expect(err).To(Succeed())
+				Expect(index).To(Equal(1))
+				values = append(values, v)
+			}
+		}, sched, "receiver").Start()
+
+		processing.NewDependencyTrigger(nil, receiver, sender).Wait(nil)
+		Expect(indices).To(Equal([]int{1, 1, 1}))
+		Expect(values).To(Equal([]int{1, 2, 3}))
+	})
+
+	It("passes the message of a waiting select to a receiver", func() {
+		c := processing.NewChannel[int](0, "c")
+
+		var index int
+		sender := processing.NewExecution(func(op processing.Operation) {
+			index, _ = processing.Select(op, processing.SendCase(c, 1))
+		}, sched, "sender").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+
+		var value int
+		receiver := processing.NewExecution(func(op processing.Operation) {
+			value, _ = c.Receive(op)
+		}, sched, "receiver").Start()
+
+		processing.NewDependencyTrigger(nil, receiver, sender).Wait(nil)
+		Expect(index).To(Equal(0))
+		Expect(value).To(Equal(1))
+	})
+
+	It("is interrupted", func() {
+		c := processing.NewChannel[int](1, "c")
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var index int
+		var err error
+		e := processing.NewExecutionWithContext(ctx, func(op processing.Operation) {
+			index, err = processing.Select(op, processing.ReceiveCase[int](c, nil))
+		}, sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+		cancel()
+		e.Wait(nil)
+		Expect(index).To(Equal(-1))
+		Expect(err).To(MatchError(context.Canceled))
+	})
+})
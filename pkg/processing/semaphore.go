@@ -0,0 +1,142 @@
+package processing
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Semaphore is a counting semaphore for operations.
+// It can be used to limit the concurrent access to a resource
+// independently of the processor limit of the Scheduler.
+// Blocked operations release their processor. Requests are
+// served in FIFO order, a waiting request blocks all
+// later requests, even if they could be satisfied.
+type Semaphore = *semaphore
+
+type semaphore struct {
+	lock sync.Mutex
+
+	waiting  Queue
+	requests []request
+	capacity int
+	used     int
+}
+
+type request struct {
+	op Operation
+	n  int
+}
+
+func NewSemaphore(capacity int, names ...string) Semaphore {
+	return &semaphore{
+		waiting:  NewQueue(ElementName("semaphore", names...)),
+		capacity: capacity,
+	}
+}
+
+// Available returns the number of actually available units.
+func (s *semaphore) Available() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.capacity - s.used
+}
+
+// Acquire acquires n units of the semaphore. If the request
+// fails or the wait is interrupted, Acquire panics with the error
+// to stop the operation. Use AcquireE to handle this situation.
+func (s *semaphore) Acquire(o Operation, n int) {
+	must(s.AcquireE(o, n))
+}
+
+// AcquireE acquires n units of the semaphore. If the wait is interrupted,
+// the error is returned and nothing is acquired.
+// The number of units must be positive.
+func (s *semaphore) AcquireE(o Operation, n int) error {
+	if err := checkUnits(n); err != nil {
+		return err
+	}
+	if n > s.capacity {
+		return fmt.Errorf("semaphore request %d exceeds capacity %d", n, s.capacity)
+	}
+	s.lock.Lock()
+
+	if len(s.requests) == 0 && s.used+n <= s.capacity {
+		s.used += n
+		s.lock.Unlock()
+		return nil
+	}
+	s.requests = append(s.requests, request{o, n})
+	err := o.BlockE(s.waiting, s.lock.Unlock)
+	if err != nil {
+		s.lock.Lock()
+		for i, r := range s.requests {
+			if r.op == o {
+				s.requests = append(s.requests[:i], s.requests[i+1:]...)
+				break
+			}
+		}
+		// a removed request might enable following requests
+		s.grant()
+		s.lock.Unlock()
+	}
+	return err
+}
+
+// TryAcquire acquires n units of the semaphore, if this
+// is possible without blocking. It panics for a non-positive
+// number of units.
+func (s *semaphore) TryAcquire(o Operation, n int) bool {
+	if err := checkUnits(n); err != nil {
+		panic(err)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.requests) > 0 || s.used+n > s.capacity {
+		return false
+	}
+	s.used += n
+	return true
+}
+
+// Release releases n units of the semaphore and passes
+// them to waiting operations. It panics for a non-positive
+// number of units.
+func (s *semaphore) Release(n int) {
+	if err := checkUnits(n); err != nil {
+		panic(err)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if n > s.used {
+		panic("semaphore released more than held")
+	}
+	s.used -= n
+	s.grant()
+}
+
+func checkUnits(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("invalid semaphore units %d", n)
+	}
+	return nil
+}
+
+func (s *semaphore) grant() {
+	for len(s.requests) > 0 {
+		r := s.requests[0]
+		if s.used+r.n > s.capacity {
+			break
+		}
+		s.requests = s.requests[1:]
+		if !s.waiting.Remove(r.op) {
+			// already interrupted
+			continue
+		}
+		r.op._removedFromQueue(s.waiting)
+		s.used += r.n
+		r.op.Unblock()
+	}
+}
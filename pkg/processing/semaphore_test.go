@@ -0,0 +1,89 @@
+package processing_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/processing/pkg/processing"
+)
+
+var _ = Describe("semaphore", func() {
+	var sched processing.Scheduler
+	var results *LockResults
+	var sem processing.Semaphore
+
+	BeforeEach(func() {
+		sched = processing.New(1)
+		results = &LockResults{}
+		sem = processing.NewSemaphore(2)
+	})
+
+	acquire := func(name string, n int, wait processing.Trigger) processing.OperationFunction {
+		return func(op processing.Operation) {
+			sem.Acquire(op, n)
+			results.Add(LOCK, name)
+			if wait != nil {
+				wait.Wait(op)
+			}
+			sem.Release(n)
+		}
+	}
+
+	It("limits concurrent access in FIFO order", func() {
+		release := processing.NewTrigger()
+		e1 := processing.NewExecution(acquire("e1", 1, release), sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+		Expect(sem.Available()).To(Equal(1))
+
+		e2 := processing.NewExecution(acquire("e2", 2, nil), sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(2))
+		e3 := processing.NewExecution(acquire("e3", 1, nil), sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(3))
+		Expect(sem.Available()).To(Equal(1))
+
+		release.Arm()
+		release.Trigger()
+		processing.NewDependencyTrigger(nil, e1, e2, e3).Wait(nil)
+		Expect(results.list).To(Equal([]string{
+			LOCK.R("e1"),
+			LOCK.R("e2"),
+			LOCK.R("e3"),
+		}))
+		Expect(sem.Available()).To(Equal(2))
+		Expect(sched.ActiveCount()).To(Equal(0))
+	})
+
+	It("acquires without blocking", func() {
+		Expect(sem.TryAcquire(nil, 2)).To(BeTrue())
+		Expect(sem.TryAcquire(nil, 1)).To(BeFalse())
+		sem.Release(1)
+		Expect(sem.TryAcquire(nil, 1)).To(BeTrue())
+		Expect(sem.AcquireE(nil, 3)).To(MatchError("semaphore request 3 exceeds capacity 2"))
+	})
+
+	It("rejects non-positive units", func() {
+		Expect(sem.AcquireE(nil, 0)).To(MatchError("invalid semaphore units 0"))
+		Expect(sem.AcquireE(nil, -1)).To(MatchError("invalid semaphore units -1"))
+		Expect(func() { sem.TryAcquire(nil, -1) }).To(PanicWith(MatchError("invalid semaphore units -1")))
+		Expect(func() { sem.Release(0) }).To(PanicWith(MatchError("invalid semaphore units 0")))
+		Expect(sem.Available()).To(Equal(2))
+	})
+
+	It("stops operations interrupted in Acquire", func() {
+		Expect(sem.TryAcquire(nil, 2)).To(BeTrue())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		e := processing.NewExecutionWithContext(ctx, acquire("e", 1, nil), sched).Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+
+		cancel()
+		e.Wait(nil)
+		Expect(results.list).To(BeEmpty())
+		Expect(e.Err()).To(MatchError(context.Canceled))
+		Expect(e.State()).To(Equal(processing.Cancelled))
+		Expect(sem.Available()).To(Equal(0))
+	})
+})
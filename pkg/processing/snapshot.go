@@ -0,0 +1,73 @@
+package processing
+
+import (
+	"sort"
+	"time"
+)
+
+// OperationInfo describes the state of a single operation
+// in a Snapshot.
+type OperationInfo struct {
+	Name     string         `json:"name"`
+	State    ExecutionState `json:"state"`
+	Priority int            `json:"priority"`
+	// Queue is the name of the queue the operation is managed in.
+	Queue string `json:"queue,omitempty"`
+	// BlockedSince is the time the operation has been blocked,
+	// it is zero, if the operation is not blocked.
+	BlockedSince time.Time `json:"blockedSince"`
+	// WaitingFor lists the operations holding the lock the
+	// blocked operation is waiting for.
+	WaitingFor []string `json:"waitingFor,omitempty"`
+}
+
+// Snapshot is a consistent view of all operations
+// of a Scheduler.
+type Snapshot struct {
+	Time       time.Time       `json:"time"`
+	Limit      int             `json:"limit"`
+	Running    int             `json:"running"`
+	Ready      int             `json:"ready"`
+	Blocked    int             `json:"blocked"`
+	Closed     bool            `json:"closed"`
+	Operations []OperationInfo `json:"operations"`
+}
+
+// Snapshot returns a consistent view of the actual state of
+// all started but not yet finished operations ordered by name.
+func (s *scheduler) Snapshot() Snapshot {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	snap := Snapshot{
+		Time:       time.Now(),
+		Limit:      s.num_processors,
+		Running:    s.running.Len(),
+		Ready:      s.ready.Len(),
+		Blocked:    s.bcnt,
+		Closed:     s.closed,
+		Operations: []OperationInfo{},
+	}
+	for o := range s.operations {
+		info := OperationInfo{
+			Name:     o.Name(),
+			State:    o.State(),
+			Priority: o.Priority(),
+		}
+		if q := o._queue(); q != nil {
+			info.Queue = q.Name()
+		}
+		if q, f := o._waitingFor(); q != nil {
+			info.BlockedSince = o.since
+			if f != nil {
+				// wait-for functions only use leaf locks
+				for _, w := range f() {
+					info.WaitingFor = append(info.WaitingFor, w.Name())
+				}
+			}
+		}
+		snap.Operations = append(snap.Operations, info)
+	}
+	sort.Slice(snap.Operations, func(i, j int) bool { return snap.Operations[i].Name < snap.Operations[j].Name })
+	return snap
+}
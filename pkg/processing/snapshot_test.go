@@ -0,0 +1,59 @@
+package processing_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/processing/pkg/processing"
+)
+
+var _ = Describe("snapshot", func() {
+	It("describes the operations", func() {
+		sched := processing.New(1)
+		release := processing.NewTrigger("release")
+		m := processing.NewMutex("m")
+
+		start := time.Now()
+		e1 := processing.NewExecution(func(op processing.Operation) {
+			m.Lock(op)
+			release.Wait(op)
+			m.Unlock()
+		}, sched, "e1").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+		e2 := processing.NewExecution(func(op processing.Operation) {
+			m.Lock(op)
+			m.Unlock()
+		}, sched, "e2").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(2))
+
+		snap := sched.Snapshot()
+		Expect(snap.Limit).To(Equal(1))
+		Expect(snap.Blocked).To(Equal(2))
+		Expect(snap.Operations).To(HaveLen(2))
+
+		o := snap.Operations[0]
+		Expect(o.Name).To(Equal("execution:e1"))
+		Expect(o.State).To(Equal(processing.Blocked))
+		Expect(o.Queue).To(Equal("trigger:release"))
+		Expect(o.BlockedSince).To(BeTemporally(">=", start))
+		Expect(o.WaitingFor).To(BeEmpty())
+
+		o = snap.Operations[1]
+		Expect(o.Name).To(Equal("execution:e2"))
+		Expect(o.State).To(Equal(processing.Blocked))
+		Expect(o.Queue).To(Equal("mutex:m"))
+		Expect(o.WaitingFor).To(Equal([]string{"execution:e1"}))
+
+		data, err := json.Marshal(o)
+		Expect(err).To(Succeed())
+		Expect(string(data)).To(ContainSubstring(`"state":"Blocked"`))
+
+		release.Arm()
+		release.Trigger()
+		processing.NewDependencyTrigger(nil, e1, e2).Wait(nil)
+		Expect(sched.Snapshot().Operations).To(BeEmpty())
+	})
+})
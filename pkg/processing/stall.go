@@ -0,0 +1,129 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrStalled = fmt.Errorf("scheduler stalled")
+
+// Stall describes a situation where all operations of a
+// Scheduler are blocked, and none of them waits with a timeout.
+// Queues contains the names of the queues the operations are
+// blocked in.
+type Stall struct {
+	Operations []string
+	Queues     []string
+}
+
+func (s Stall) String() string {
+	var parts []string
+	for i, n := range s.Operations {
+		parts = append(parts, fmt.Sprintf("%s (%s)", n, s.Queues[i]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// StallHandler is called whenever a Scheduler gets stalled.
+type StallHandler func(Stall)
+
+// WithStallHandler sets a handler called whenever all operations of
+// the Scheduler get blocked. The stall detection assumes that blocked
+// operations are only unblocked by other operations of the Scheduler.
+// Go routines not executed as operation might still resolve a reported
+// stall.
+func WithStallHandler(h StallHandler) Option {
+	return func(s *scheduler) {
+		s.stallHandler = h
+	}
+}
+
+// Wait waits until all started operations are finished.
+// If the scheduler gets stalled before, an error wrapping ErrStalled
+// and listing the blocked operations is returned.
+func (s *scheduler) Wait(ctx context.Context) error {
+	s.lock.Lock()
+	idle := s._idle()
+	stall := s._stall()
+	s.lock.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-stall:
+		s.lock.Lock()
+		stalled := s.stalled
+		s.lock.Unlock()
+		if stalled == nil {
+			// already resolved
+			return s.Wait(ctx)
+		}
+		return fmt.Errorf("%w: %s", ErrStalled, stalled)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// _stall provides a channel closed once the scheduler is stalled.
+func (s *scheduler) _stall() <-chan struct{} {
+	if s.stallch == nil {
+		s.stallch = make(chan struct{})
+		if s.stalled != nil {
+			close(s.stallch)
+		}
+	}
+	return s.stallch
+}
+
+// _checkStall checks whether the scheduler got stalled.
+// A newly detected stall is returned and must be reported
+// by the caller after releasing the scheduler lock.
+func (s *scheduler) _checkStall() *Stall {
+	if s.stalled != nil || len(s.operations) == 0 || s.bcnt != len(s.operations) ||
+		s.running.Len() != 0 || s.ready.Len() != 0 {
+		return nil
+	}
+	var ops []State
+	for o := range s.operations {
+		if o.timed || o._blockingQueue() == nil {
+			// operations already dequeued for a wake-up
+			// are about to be unblocked.
+			return nil
+		}
+		ops = append(ops, o)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Name() < ops[j].Name() })
+
+	stall := &Stall{}
+	for _, o := range ops {
+		name := ""
+		if q := o._blockingQueue(); q != nil {
+			name = q.Name()
+		}
+		stall.Operations = append(stall.Operations, o.Name())
+		stall.Queues = append(stall.Queues, name)
+	}
+	s.stalled = stall
+	if s.stallch != nil {
+		close(s.stallch)
+	}
+	return stall
+}
+
+// _resolveStall resets a detected stall, because
+// an operation could proceed.
+func (s *scheduler) _resolveStall() {
+	if s.stalled != nil {
+		s.stalled = nil
+		s.stallch = nil
+	}
+}
+
+// reportStall calls the stall handler for a newly detected stall.
+func (s *scheduler) reportStall(stall *Stall) {
+	if stall != nil && s.stallHandler != nil {
+		s.stallHandler(*stall)
+	}
+}
@@ -0,0 +1,158 @@
+package processing_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/processing/pkg/processing"
+)
+
+var _ = Describe("stall detection", func() {
+	It("reports stalled operations", func() {
+		var lock sync.Mutex
+		var stalls []processing.Stall
+		sched := processing.New(2, processing.WithStallHandler(func(s processing.Stall) {
+			lock.Lock()
+			defer lock.Unlock()
+			stalls = append(stalls, s)
+		}))
+
+		never := processing.NewTrigger("never")
+		m := processing.NewMutex("m")
+		processing.NewExecution(func(op processing.Operation) {
+			m.Lock(op)
+			never.Wait(op)
+			m.Unlock()
+		}, sched, "e1").Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+		processing.NewExecution(func(op processing.Operation) {
+			m.Lock(op)
+			m.Unlock()
+		}, sched, "e2").Start()
+
+		err := sched.Wait(context.Background())
+		Expect(err).To(MatchError(processing.ErrStalled))
+		Expect(err.Error()).To(Equal("scheduler stalled: execution:e1 (trigger:never), execution:e2 (mutex:m)"))
+
+		// e1 alone is already stalled before e2 is started
+		Eventually(func() int {
+			lock.Lock()
+			defer lock.Unlock()
+			return len(stalls)
+		}, 5*time.Second).Should(Equal(2))
+		lock.Lock()
+		Expect(stalls[0].Operations).To(Equal([]string{"execution:e1"}))
+		Expect(stalls[1].Operations).To(Equal([]string{"execution:e1", "execution:e2"}))
+		Expect(stalls[1].Queues).To(Equal([]string{"trigger:never", "mutex:m"}))
+		lock.Unlock()
+		sched.Close()
+	})
+
+	It("ignores operations the mutex is passed to", func() {
+		for i := 0; i < 100; i++ {
+			sched := processing.New(2)
+			m := processing.NewMutex("m")
+			locked := processing.NewTrigger()
+			processing.NewExecution(func(op processing.Operation) {
+				m.Lock(op)
+				locked.Wait(op)
+				m.Unlock()
+			}, sched, "e1").Start()
+			Eventually(m.Info, 5*time.Second).Should(HaveField("Locked", true))
+			processing.NewExecution(func(op processing.Operation) {
+				m.Lock(op)
+				m.Unlock()
+			}, sched, "e2").Start()
+			Eventually(m.Info, 5*time.Second).Should(HaveField("Waiting", 1))
+			locked.Arm()
+			locked.Trigger()
+
+			Expect(sched.Wait(context.Background())).To(Succeed())
+		}
+	})
+
+	It("ignores operations the mutex is passed to by a custom queue", func() {
+		policy := func(name string) processing.Queue { return &fifo{name: name} }
+		for i := 0; i < 100; i++ {
+			sched := processing.New(2, processing.WithReadyQueue(policy))
+			m := processing.NewMutexWithQueue(policy, "m")
+			locked := processing.NewTrigger()
+			processing.NewExecution(func(op processing.Operation) {
+				m.Lock(op)
+				locked.Wait(op)
+				m.Unlock()
+			}, sched, "e1").Start()
+			Eventually(m.Info, 5*time.Second).Should(HaveField("Locked", true))
+			processing.NewExecution(func(op processing.Operation) {
+				m.Lock(op)
+				m.Unlock()
+			}, sched, "e2").Start()
+			Eventually(m.Info, 5*time.Second).Should(HaveField("Waiting", 1))
+			locked.Arm()
+			locked.Trigger()
+
+			Expect(sched.Wait(context.Background())).To(Succeed())
+			Expect(sched.Snapshot().Operations).To(BeEmpty())
+		}
+	})
+
+	It("ignores operations waiting with timeout", func() {
+		sched := processing.New(2)
+		never := processing.NewTrigger("never")
+		processing.NewExecution(func(op processing.Operation) {
+			never.WaitTimeout(op, 100*time.Millisecond)
+		}, sched, "e1").Start()
+
+		Expect(sched.Wait(context.Background())).To(Succeed())
+	})
+})
+
+// fifo is a Queue implemented outside of the processing package.
+type fifo struct {
+	lock sync.Mutex
+	name string
+	list []processing.Operation
+}
+
+func (q *fifo) Name() string {
+	return q.name
+}
+
+func (q *fifo) Add(op processing.Operation) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.list = append(q.list, op)
+}
+
+func (q *fifo) Remove(op processing.Operation) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for i, e := range q.list {
+		if e == op {
+			q.list = append(q.list[:i], q.list[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (q *fifo) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.list)
+}
+
+func (q *fifo) Next() processing.Operation {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if len(q.list) == 0 {
+		return nil
+	}
+	op := q.list[0]
+	q.list = q.list[1:]
+	return op
+}
@@ -1,7 +1,13 @@
 package processing
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type State = *state
@@ -18,40 +24,221 @@ var _ Operation = (State)(nil)
 // in any object and shared with other Go routines.
 type OperationFunction func(Operation)
 
+// PanicError is the error reported for an operation,
+// whose function panicked. This is also the case, if a
+// blocking call without error result, like Mutex.Lock,
+// is interrupted.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func newPanicError(v interface{}) *PanicError {
+	return &PanicError{Value: v, Stack: debug.Stack()}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("operation panicked: %v", e.Value)
+}
+
+// Unwrap provides the panic value, if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// ExecutionState describes the processing state of an operation.
+// Done, Failed, Cancelled and Skipped are terminal states.
+type ExecutionState int
+
+const (
+	// Pending operations are not started, yet.
+	Pending ExecutionState = iota
+	// Ready operations are waiting for a processor.
+	Ready
+	// Running operations are executed on a processor.
+	Running
+	// Blocked operations are waiting in a synchronization primitive.
+	Blocked
+	// Done operations are finished without error.
+	Done
+	// Failed operations are finished with an error or a panic.
+	Failed
+	// Cancelled operations are finished because of a cancelled context,
+	// an abort or a shutdown of the scheduler.
+	Cancelled
+	// Skipped tasks are not executed because of failed dependencies.
+	Skipped
+)
+
+var stateNames = []string{"Pending", "Ready", "Running", "Blocked", "Done", "Failed", "Cancelled", "Skipped"}
+
+func (s ExecutionState) String() string {
+	if s < 0 || int(s) >= len(stateNames) {
+		return fmt.Sprintf("ExecutionState(%d)", int(s))
+	}
+	return stateNames[s]
+}
+
+// MarshalText provides the state name for textual encodings like JSON.
+func (s ExecutionState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText parses a state name.
+func (s *ExecutionState) UnmarshalText(data []byte) error {
+	for i, n := range stateNames {
+		if n == string(data) {
+			*s = ExecutionState(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown execution state %q", string(data))
+}
+
+// IsTerminal reports whether the state is a final state.
+func (s ExecutionState) IsTerminal() bool {
+	return s >= Done
+}
+
+func isCancellation(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrAborted) || errors.Is(err, ErrShutdown)
+}
+
+// ids provides process-wide unique operation ids.
+var ids atomic.Uint64
+
 type state struct {
 	lock      sync.Mutex
+	id        uint64
 	name      string
+	priority  atomic.Int64
 	self      interface{}
+	ctx       context.Context
 	scheduler Scheduler
 	blocker   sync.Mutex
 
-	done    Trigger
-	blocked bool
+	done            Trigger
+	stop            chan struct{}
+	status          ExecutionState
+	blocked         bool
+	blocks          uint64    // guarded by scheduler lock
+	timed           bool      // guarded by scheduler lock
+	uninterruptible bool      // guarded by scheduler lock
+	deadlocked      uint64    // block reported as deadlocked, guarded by scheduler lock
+	since           time.Time // guarded by scheduler lock
+	blockedIn       string    // guarded by scheduler lock
+	queued          time.Time
+	wakeup          error
+	aborted         bool // a blocking call has been aborted
+	err             error
+	waitFor         func() []Operation
 
 	queue Queue
 }
 
+// ID returns a process-wide unique id for the operation.
+func (s *state) ID() uint64 {
+	return s.id
+}
+
 func (s *state) Name() string {
 	return s.name
 }
 
+// Priority returns the scheduling priority of the operation.
+func (s *state) Priority() int {
+	return int(s.priority.Load())
+}
+
+// SetPriority sets the scheduling priority of the operation.
+// Ready operations with a higher priority are scheduled first.
+// It may be changed at any time, also while the operation
+// is waiting for a processor.
+func (s *state) SetPriority(p int) {
+	s.priority.Store(int64(p))
+}
+
+func (s *state) Context() context.Context {
+	return s.ctx
+}
+
 func (s *state) Preempt() {
 	s._preempt()
-	s.blocker.Lock()
 }
 
-func (s *state) skip() {
+// skip finishes an operation without executing it.
+func (s *state) skip(status ExecutionState) {
 	s.lock.Lock()
-	defer s.lock.Unlock()
+	s.status = status
+	s.lock.Unlock()
+	s.scheduler.finished(s, status)
 	s.done.Trigger()
 }
 
+// State returns the actual processing state of the operation.
+func (s *state) State() ExecutionState {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.status != Running {
+		return s.status
+	}
+	switch {
+	case s.blocked:
+		return Blocked
+	case s.queue == s.scheduler.ready:
+		return Ready
+	default:
+		return Running
+	}
+}
+
+func (s *state) _started() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.status = Running
+}
+
+// _finish determines the terminal state of an executed operation.
+func (s *state) _finish() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.err == nil && s.aborted {
+		// the function handled the abort and returned regularly
+		s.err = ErrAborted
+	}
+	switch {
+	case s.err == nil && s.ctx.Err() == nil:
+		s.status = Done
+	case s.err == nil || isCancellation(s.err):
+		s.status = Cancelled
+	default:
+		s.status = Failed
+	}
+}
+
 func (s *state) IsDone() bool {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	return s.done.IsTriggered()
 }
 
+// Err returns the error the operation finished with.
+func (s *state) Err() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.err
+}
+
+func (s *state) _setErr(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.err = err
+}
+
 func (s *state) IsBlocked() bool {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -66,6 +253,86 @@ func (s *state) _unblock() {
 	s.blocker.Unlock()
 }
 
+// _blockingQueue returns the queue the operation is
+// actually blocked in, if it is waiting for an unblock.
+func (s *state) _blockingQueue() Queue {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.blocked {
+		return s.queue
+	}
+	return nil
+}
+
+// _queue returns the queue the operation is actually
+// managed in.
+func (s *state) _queue() Queue {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.queue
+}
+
+// _queuedFor returns the time the operation is
+// managed by its actual queue.
+func (s *state) _queuedFor() time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return time.Since(s.queued)
+}
+
+// _setWaitFor sets the function providing the operations
+// a blocked operation is waiting for. It is used to build
+// the wait-for graph for the deadlock detection.
+func (s *state) _setWaitFor(f func() []Operation) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.waitFor = f
+}
+
+// _waitingFor returns the queue and the function providing
+// the operations a blocked operation is waiting for.
+func (s *state) _waitingFor() (Queue, func() []Operation) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.blocked && s.queue != nil {
+		return s.queue, s.waitFor
+	}
+	return nil, nil
+}
+
+// _setWakeup sets the reason for the next wake-up
+// of a blocked operation.
+func (s *state) _setWakeup(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.wakeup = err
+}
+
+// _noteAbort records, whether a blocking call has been
+// aborted by closing the scheduler. Such an operation is
+// cancelled, even if the function handles the error.
+func (s *state) _noteAbort(err error) {
+	if errors.Is(err, ErrAborted) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.aborted = true
+	}
+}
+
+// _getWakeup returns and resets the reason for the
+// last wake-up.
+func (s *state) _getWakeup() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	err := s.wakeup
+	s.wakeup = nil
+	return err
+}
+
 func (s *state) _removedFromQueue(q Queue) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -85,6 +352,7 @@ func (s *state) _addToQueue(q Queue, blocked bool) {
 			s.queue.Remove(s)
 		}
 		s.queue = q
+		s.queued = time.Now()
 		if q != nil {
 			q.Add(s)
 		}
@@ -92,11 +360,45 @@ func (s *state) _addToQueue(q Queue, blocked bool) {
 }
 
 func (s *state) Block(q Queue, r ReleaseFunction) {
-	s.scheduler.block(s, q, r)
+	s.scheduler.block(s, q, r, time.Time{})
+}
+
+// BlockE blocks the operation in the given queue.
+// The release function is called after the operation has been
+// added to the queue. The error given by the wake-up
+// is returned. If an error is returned because the wait
+// has been interrupted, the operation has already been removed
+// from the queue.
+func (s *state) BlockE(q Queue, r ReleaseFunction) error {
+	return s.scheduler.block(s, q, r, time.Time{})
+}
+
+// BlockTimeout is like BlockE, but the wait is interrupted
+// with ErrTimeout after the given duration. A non-positive
+// duration times out immediately.
+func (s *state) BlockTimeout(q Queue, r ReleaseFunction, d time.Duration) error {
+	return s.scheduler.block(s, q, r, time.Now().Add(d))
+}
+
+// _blockUntil is like BlockE, but with an optional deadline.
+func (s *state) _blockUntil(q Queue, r ReleaseFunction, deadline time.Time) error {
+	return s.scheduler.block(s, q, r, deadline)
+}
+
+// _blockUninterruptible blocks until the operation is unblocked
+// regularly, interruptions are ignored.
+func (s *state) _blockUninterruptible(q Queue, r ReleaseFunction) {
+	s.scheduler.blockUninterruptible(s, q, r)
 }
 
 func (s *state) Unblock() {
-	s.scheduler.unblock(s)
+	s.scheduler.unblock(s, nil)
+}
+
+// UnblockE unblocks the operation, the given error
+// is returned by the BlockE call of the operation.
+func (s *state) UnblockE(err error) {
+	s.scheduler.unblock(s, err)
 }
 
 func (s *state) _preempt() {
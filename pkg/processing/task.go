@@ -1,13 +1,21 @@
 package processing
 
+import (
+	"context"
+	"sync"
+)
+
 type TaskFunction[R any] func(Operation) (R, error)
 
 type AnyTask interface {
 	Start()
+	SetPriority(int)
+	Priority() int
 	RegisterAction(a TriggerAction)
 	DependsOn(deps ...Dependency) error
 	IsSkipped() bool
 	Status() error
+	State() ExecutionState
 }
 
 // A Task is an Execution for a TaskFunction whose execution is dependent
@@ -20,6 +28,8 @@ type AnyTask interface {
 // the current task is skipped, which can be checked with the method
 // AnyTask.IsSkipped().
 // The actual status (error code) can be queried by the method AnyTask.Status().
+// If the TaskFunction panics, the status is a *PanicError.
+// The processing state can be queried by the method AnyTask.State().
 type Task[R any] interface {
 	AnyTask
 	Wait(Operation) (R, error)
@@ -28,17 +38,26 @@ type Task[R any] interface {
 type task[R any] struct {
 	trigger   Trigger
 	execution Execution
-	deps      []AnyTask
-	skipped   bool
 	result    R
-	err       error
+
+	deplock sync.Mutex // guards deps, may be acquired under the scheduler lock
+	deps    []AnyTask
 }
 
 func NewTask[R any](f TaskFunction[R], s Scheduler, names ...string) Task[R] {
+	return NewTaskWithContext[R](context.Background(), f, s, names...)
+}
+
+// NewTaskWithContext creates a Task bound to the given context.
+// If the context is cancelled before the task is executed, the
+// TaskFunction is not called anymore. If it is cancelled while the task
+// is running, blocked synchronization primitives are interrupted.
+// In both cases the task status reports the cancellation error.
+func NewTaskWithContext[R any](ctx context.Context, f TaskFunction[R], s Scheduler, names ...string) Task[R] {
 	t := &task[R]{
 		trigger: NewTrigger(),
 	}
-	t.execution = newExecution(func(op Operation) { t.run(op, f) }, s, t, "task", names...)
+	t.execution = newExecution(ctx, func(op Operation) { t.run(op, f) }, s, t, "task", names...)
 	t.trigger.RegisterAction(t.start)
 	return t
 }
@@ -48,49 +67,76 @@ func (t *task[R]) Start() {
 	t.trigger.Trigger()
 }
 
+func (t *task[R]) SetPriority(p int) {
+	t.execution.SetPriority(p)
+}
+
+func (t *task[R]) Priority() int {
+	return t.execution.Priority()
+}
+
 func (t *task[R]) IsSkipped() bool {
-	t.execution.lock.Lock()
-	defer t.execution.lock.Unlock()
-	return t.skipped
+	return t.State() == Skipped
 }
 
 func (t *task[R]) Status() error {
-	t.execution.lock.Lock()
-	defer t.execution.lock.Unlock()
-	return t.err
+	return t.execution.Err()
 }
 
+func (t *task[R]) State() ExecutionState {
+	return t.execution.State()
+}
+
+// Wait waits for the task to be finished and returns its result.
+// If the wait is interrupted, the error is returned instead.
 func (t *task[R]) Wait(op Operation) (R, error) {
-	t.execution.Wait(op)
+	if err := t.execution.WaitE(op); err != nil {
+		var zero R
+		return zero, err
+	}
 
 	t.execution.lock.Lock()
 	defer t.execution.lock.Unlock()
-	return t.result, t.err
+	return t.result, t.execution.Err()
 }
 
 func (t *task[R]) start(Trigger) {
-	t.execution.lock.Lock()
-	for _, d := range t.deps {
-		t.err = d.Status()
-		if t.err != nil {
+	var err error
+
+	t.deplock.Lock()
+	deps := t.deps
+	t.deplock.Unlock()
+	for _, d := range deps {
+		err = d.Status()
+		if err != nil {
 			break
 		}
 	}
-	t.execution.lock.Unlock()
-	if t.err == nil {
-		t.execution.Start()
-	} else {
-		t.execution.state.skip()
-		t.skipped = true
+	if err != nil {
+		t.execution.state._setErr(err)
+		t.execution.state.skip(Skipped)
+		return
+	}
+	if err = t.execution.start(); err != nil {
+		t.execution.state._setErr(err)
+		t.execution.state.skip(Cancelled)
 	}
 }
 
 func (t *task[R]) run(op Operation, f TaskFunction[R]) {
-	r, err := f(op)
+	var r R
+
+	err := op.Context().Err()
+	if err == nil {
+		r, err = f(op)
+		if err == nil {
+			err = op.Context().Err()
+		}
+	}
 
 	t.execution.lock.Lock()
 	defer t.execution.lock.Unlock()
-	t.err = err
+	t.execution.state._setErr(err)
 	t.result = r
 }
 
@@ -98,6 +144,37 @@ func (t *task[R]) RegisterAction(a TriggerAction) {
 	t.execution.RegisterAction(a)
 }
 
+func (t *task[R]) operation() Operation {
+	return t.execution.state
+}
+
+// dependencies returns the operations of the tasks
+// the task depends on.
+func (t *task[R]) dependencies() []Operation {
+	t.deplock.Lock()
+	defer t.deplock.Unlock()
+
+	var ops []Operation
+	for _, d := range t.deps {
+		if o, ok := d.(interface{ operation() Operation }); ok {
+			ops = append(ops, o.operation())
+		}
+	}
+	return ops
+}
+
+// DependenciesOf returns the operations of the tasks the task
+// executed by the given operation depends on. For operations
+// of other executions no dependencies are returned.
+func DependenciesOf(op Operation) []Operation {
+	if s, ok := op.(State); ok {
+		if t, ok := s.self.(interface{ dependencies() []Operation }); ok {
+			return t.dependencies()
+		}
+	}
+	return nil
+}
+
 func (t *task[R]) DependsOn(deps ...Dependency) error {
 	t.execution.lock.Lock()
 	defer t.execution.lock.Unlock()
@@ -108,7 +185,9 @@ func (t *task[R]) DependsOn(deps ...Dependency) error {
 			return err
 		}
 		if a, ok := d.(AnyTask); ok {
+			t.deplock.Lock()
 			t.deps = append(t.deps, a)
+			t.deplock.Unlock()
 		}
 	}
 	return nil
@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/mandelsoft/processing/pkg/processing"
+)
+
+// Listener is a processing.SchedulerListener creating a span for
+// every operation of a scheduler. All spans of a Listener belong
+// to the same trace. The span id is derived from the operation id,
+// therefore the span of a task can be linked to the spans of the
+// tasks it depends on, even if they have already been ended.
+type Listener struct {
+	processing.SchedulerListenerBase
+
+	lock    sync.Mutex
+	tracer  Tracer
+	traceID TraceID
+	spans   map[processing.Operation]Span
+}
+
+var _ processing.SchedulerListener = (*Listener)(nil)
+
+func NewListener(t Tracer) *Listener {
+	l := &Listener{
+		tracer: t,
+		spans:  map[processing.Operation]Span{},
+	}
+	rand.Read(l.traceID[:])
+	return l
+}
+
+// TraceID returns the id of the trace used for the spans.
+func (l *Listener) TraceID() TraceID {
+	return l.traceID
+}
+
+// SpanContext returns the context of the span for the given operation.
+func (l *Listener) SpanContext(op processing.Operation) SpanContext {
+	sc := SpanContext{TraceID: l.traceID}
+	binary.BigEndian.PutUint64(sc.SpanID[:], op.ID())
+	return sc
+}
+
+func (l *Listener) start(op processing.Operation, t time.Time) Span {
+	var links []SpanContext
+	for _, d := range processing.DependenciesOf(op) {
+		links = append(links, l.SpanContext(d))
+	}
+	span := l.tracer.Start(l.SpanContext(op), op.Name(), t, links, Int("priority", op.Priority()))
+	l.spans[op] = span
+	return span
+}
+
+func (l *Listener) event(op processing.Operation, name string, attrs ...Attribute) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if span := l.spans[op]; span != nil {
+		span.AddEvent(name, time.Now(), attrs...)
+	}
+}
+
+func (l *Listener) Started(op processing.Operation, q processing.Queue) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	l.start(op, now).AddEvent("start", now, String("queue", q.Name()))
+}
+
+func (l *Listener) Blocked(op processing.Operation, q processing.Queue) {
+	l.event(op, "block", String("queue", q.Name()))
+}
+
+func (l *Listener) Unblocked(op processing.Operation, q processing.Queue) {
+	l.event(op, "unblock", String("queue", q.Name()))
+}
+
+func (l *Listener) Preempted(op processing.Operation, next processing.Operation) {
+	if next == nil {
+		l.event(op, "preempt")
+	} else {
+		l.event(op, "preempt", String("next", next.Name()))
+	}
+}
+
+func (l *Listener) Scheduled(op processing.Operation) {
+	l.event(op, "schedule")
+}
+
+func (l *Listener) Finished(op processing.Operation, state processing.ExecutionState) {
+	now := time.Now()
+
+	l.lock.Lock()
+	span := l.spans[op]
+	if span == nil {
+		// never started, for example skipped tasks
+		span = l.start(op, now)
+	}
+	delete(l.spans, op)
+	l.lock.Unlock()
+
+	var err error
+	if e, ok := op.(interface{ Err() error }); ok {
+		err = e.Err()
+	}
+	span.SetAttributes(String("state", state.String()))
+	span.End(now, err)
+}
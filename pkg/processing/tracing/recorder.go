@@ -0,0 +1,132 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Exporter receives ended spans.
+type Exporter interface {
+	Export(SpanData) error
+}
+
+// Recorder is a Tracer recording the span data
+// and passing ended spans to an Exporter.
+type Recorder struct {
+	exporter Exporter
+}
+
+var _ Tracer = (*Recorder)(nil)
+
+func NewRecorder(e Exporter) *Recorder {
+	return &Recorder{exporter: e}
+}
+
+func (r *Recorder) Start(sc SpanContext, name string, t time.Time, links []SpanContext, attrs ...Attribute) Span {
+	return &span{
+		exporter: r.exporter,
+		data: SpanData{
+			SpanContext: sc,
+			Name:        name,
+			Start:       t,
+			Links:       links,
+			Attributes:  attrs,
+		},
+	}
+}
+
+type span struct {
+	lock     sync.Mutex
+	exporter Exporter
+	data     SpanData
+}
+
+func (s *span) AddEvent(name string, t time.Time, attrs ...Attribute) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.data.Events = append(s.data.Events, Event{Name: name, Time: t, Attributes: attrs})
+}
+
+func (s *span) SetAttributes(attrs ...Attribute) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+func (s *span) End(t time.Time, err error) {
+	s.lock.Lock()
+	s.data.End = t
+	if err != nil {
+		s.data.Error = err.Error()
+	}
+	data := s.data
+	s.lock.Unlock()
+
+	s.exporter.Export(data)
+}
+
+// MemoryCollector is an Exporter keeping the spans in memory.
+type MemoryCollector struct {
+	lock  sync.Mutex
+	spans []SpanData
+}
+
+var _ Exporter = (*MemoryCollector)(nil)
+
+func NewMemoryCollector() *MemoryCollector {
+	return &MemoryCollector{}
+}
+
+func (c *MemoryCollector) Export(s SpanData) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.spans = append(c.spans, s)
+	return nil
+}
+
+// Spans returns the collected spans in the order they have been ended.
+func (c *MemoryCollector) Spans() []SpanData {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return append([]SpanData(nil), c.spans...)
+}
+
+// FileCollector is an Exporter writing every span as
+// a single line of JSON.
+type FileCollector struct {
+	lock sync.Mutex
+	enc  *json.Encoder
+}
+
+var _ Exporter = (*FileCollector)(nil)
+
+func NewFileCollector(w io.Writer) *FileCollector {
+	return &FileCollector{enc: json.NewEncoder(w)}
+}
+
+func (c *FileCollector) Export(s SpanData) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.enc.Encode(s)
+}
+
+// ReadSpans reads the spans written by a FileCollector.
+func ReadSpans(r io.Reader) ([]SpanData, error) {
+	var spans []SpanData
+	dec := json.NewDecoder(r)
+	for dec.More() {
+		var s SpanData
+		if err := dec.Decode(&s); err != nil {
+			return nil, err
+		}
+		spans = append(spans, s)
+	}
+	return spans, nil
+}
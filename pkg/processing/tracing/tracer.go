@@ -0,0 +1,117 @@
+// Package tracing provides tracing of executions and tasks
+// following the OpenTelemetry data model. Every operation becomes
+// a span, blocking and scheduling transitions are recorded as span
+// events, and the span of a task is linked to the spans of the tasks
+// it depends on.
+//
+// The package only defines a minimal Tracer interface, which can
+// be adapted to a full OpenTelemetry SDK. The Recorder provided
+// here implements it and passes the ended spans to an Exporter,
+// for example the MemoryCollector or the FileCollector, which can
+// be used to visualize the processing offline.
+//
+//	c := tracing.NewMemoryCollector()
+//	sched := processing.New(4, processing.WithListener(tracing.NewListener(tracing.NewRecorder(c))))
+package tracing
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type TraceID [16]byte
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+func (t TraceID) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *TraceID) UnmarshalText(data []byte) error {
+	return unmarshalID(t[:], data)
+}
+
+type SpanID [8]byte
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+func (s SpanID) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *SpanID) UnmarshalText(data []byte) error {
+	return unmarshalID(s[:], data)
+}
+
+func unmarshalID(id []byte, data []byte) error {
+	if hex.DecodedLen(len(data)) != len(id) {
+		return fmt.Errorf("invalid id %q", string(data))
+	}
+	_, err := hex.Decode(id, data)
+	return err
+}
+
+// SpanContext identifies a span.
+type SpanContext struct {
+	TraceID TraceID `json:"traceId"`
+	SpanID  SpanID  `json:"spanId"`
+}
+
+// Attribute is a key/value pair describing a span or event.
+type Attribute struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+func String(key, value string) Attribute {
+	return Attribute{key, value}
+}
+
+func Int(key string, value int) Attribute {
+	return Attribute{key, value}
+}
+
+// Tracer is a minimal interface for creating spans.
+type Tracer interface {
+	// Start starts a span with the given context and links
+	// to other spans.
+	Start(sc SpanContext, name string, t time.Time, links []SpanContext, attrs ...Attribute) Span
+}
+
+// Span is a started span.
+type Span interface {
+	AddEvent(name string, t time.Time, attrs ...Attribute)
+	SetAttributes(attrs ...Attribute)
+	// End ends the span. If err is not nil, the span status is an error.
+	End(t time.Time, err error)
+}
+
+// Event is a recorded span event.
+type Event struct {
+	Name       string      `json:"name"`
+	Time       time.Time   `json:"time"`
+	Attributes []Attribute `json:"attributes,omitempty"`
+}
+
+// SpanData is a recorded span.
+type SpanData struct {
+	SpanContext
+	Name       string        `json:"name"`
+	Start      time.Time     `json:"startTime"`
+	End        time.Time     `json:"endTime"`
+	Attributes []Attribute   `json:"attributes,omitempty"`
+	Events     []Event       `json:"events,omitempty"`
+	Links      []SpanContext `json:"links,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+func (s SpanData) String() string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
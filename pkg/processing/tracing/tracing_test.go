@@ -0,0 +1,85 @@
+package tracing_test
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/mandelsoft/processing/pkg/processing"
+	"github.com/mandelsoft/processing/pkg/processing/tracing"
+)
+
+func events(s tracing.SpanData) []string {
+	var r []string
+	for _, e := range s.Events {
+		n := e.Name
+		for _, a := range e.Attributes {
+			n += fmt.Sprintf(" %s=%v", a.Key, a.Value)
+		}
+		r = append(r, n)
+	}
+	return r
+}
+
+var _ = Describe("tracing", func() {
+	It("traces tasks", func() {
+		var buf bytes.Buffer
+		c := tracing.NewMemoryCollector()
+		l := tracing.NewListener(tracing.NewRecorder(c))
+		f := tracing.NewListener(tracing.NewRecorder(tracing.NewFileCollector(&buf)))
+		sched := processing.New(1, processing.WithListener(l, f))
+
+		release := processing.NewTrigger("release")
+		t1 := processing.NewTask[int](func(op processing.Operation) (int, error) {
+			release.Wait(op)
+			return 1, nil
+		}, sched, "t1")
+		t2 := processing.NewTask[int](func(op processing.Operation) (int, error) {
+			return 0, fmt.Errorf("failed")
+		}, sched, "t2")
+		t3 := processing.NewTask[int](func(op processing.Operation) (int, error) {
+			return 3, nil
+		}, sched, "t3")
+		t2.DependsOn(t1)
+		t3.DependsOn(t2)
+		t3.Start()
+		t2.Start()
+		t1.Start()
+		Eventually(sched.BlockedCount, 5*time.Second).Should(Equal(1))
+		release.Arm()
+		release.Trigger()
+		processing.NewDependencyTrigger(nil, t1, t2, t3).Wait(nil)
+
+		spans := c.Spans()
+		Expect(spans).To(HaveLen(3))
+		Expect(spans[0].Name).To(Equal("task:t1"))
+		Expect(spans[0].TraceID).To(Equal(l.TraceID()))
+		Expect(events(spans[0])).To(Equal([]string{
+			"start queue=running",
+			"block queue=trigger:release",
+			"unblock queue=running",
+		}))
+		Expect(spans[0].Links).To(BeEmpty())
+		Expect(spans[0].Error).To(Equal(""))
+
+		Expect(spans[1].Name).To(Equal("task:t2"))
+		Expect(spans[1].Links).To(Equal([]tracing.SpanContext{spans[0].SpanContext}))
+		Expect(spans[1].Error).To(Equal("failed"))
+
+		Expect(spans[2].Name).To(Equal("task:t3"))
+		Expect(spans[2].Links).To(Equal([]tracing.SpanContext{spans[1].SpanContext}))
+		Expect(spans[2].Attributes).To(ContainElement(tracing.String("state", "Skipped")))
+		Expect(spans[2].Events).To(BeEmpty())
+
+		read, err := tracing.ReadSpans(&buf)
+		Expect(err).To(Succeed())
+		Expect(read).To(HaveLen(3))
+		Expect(read[1].Name).To(Equal("task:t2"))
+		Expect(read[1].SpanID).To(Equal(spans[1].SpanID))
+		Expect(read[1].TraceID).To(Equal(f.TraceID()))
+		Expect(read[1].Links[0].SpanID).To(Equal(spans[0].SpanID))
+	})
+})
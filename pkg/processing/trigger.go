@@ -3,6 +3,7 @@ package processing
 import (
 	"fmt"
 	"sync"
+	"time"
 )
 
 var ErrArmed = fmt.Errorf("trigger already armed")
@@ -34,8 +35,13 @@ type Trigger interface {
 	Trigger()
 
 	IsTriggered() bool
+	Poll() bool
 
 	Wait(operation Operation)
+	WaitE(operation Operation) error
+	WaitTimeout(operation Operation, d time.Duration) error
+
+	Info() ElementInfo
 }
 
 // NewTrigger creates a generic unarmed Trigger.
@@ -72,7 +78,8 @@ type trigger struct {
 	triggered    bool
 	dependencies int
 
-	waiting Queue
+	waiting  Queue
+	watchers watchers
 }
 
 func (t *trigger) Arm() {
@@ -100,12 +107,13 @@ func (t *trigger) trigger() {
 		}
 
 		for {
-			if n := t.waiting.Next(); n != nil {
+			if n := dequeue(t.waiting); n != nil {
 				n.Unblock()
 			} else {
 				break
 			}
 		}
+		t.watchers.signal()
 	}
 }
 
@@ -136,13 +144,15 @@ func (t *trigger) depTriggered(d Trigger) {
 
 func (t *trigger) DependOn(deps ...Dependency) error {
 	t.lock.Lock()
-	defer t.lock.Unlock()
-
 	if t.armed {
+		t.lock.Unlock()
 		return ErrArmed
 	}
+	t.dependencies += len(deps)
+	t.lock.Unlock()
+
+	// actions of already fired dependencies are executed synchronously
 	for _, d := range deps {
-		t.dependencies++
 		d.RegisterAction(t.depTriggered)
 	}
 	return nil
@@ -155,6 +165,12 @@ func (t *trigger) IsTriggered() bool {
 	return t.isTriggered()
 }
 
+// Poll checks without blocking, whether the trigger has reached
+// the triggered state, meaning a Wait would return immediately.
+func (t *trigger) Poll() bool {
+	return t.IsTriggered()
+}
+
 func (t *trigger) isTriggered() bool {
 	return t.triggered && t.armed && t.dependencies == 0
 }
@@ -170,20 +186,47 @@ func (t *trigger) isTriggered() bool {
 // continue with another operation ready for execution.
 // If it is NOT given (nil), the actual GO routine is blocked
 // by the GO runtime instead of using the scheduler of an operation.
+// If the operation is cancelled, Wait panics with the error to stop
+// the operation. Use WaitE to handle this situation.
 func (t *trigger) Wait(op Operation) {
+	must(t.WaitE(op))
+}
+
+// WaitE is like Wait, but returns an error, if the wait
+// is interrupted before the trigger reached the triggered state.
+func (t *trigger) WaitE(op Operation) error {
+	return t.wait(op, time.Time{})
+}
+
+// WaitTimeout is like WaitE, but gives up waiting with ErrTimeout
+// after the given duration.
+func (t *trigger) WaitTimeout(op Operation, d time.Duration) error {
+	return t.wait(op, time.Now().Add(d))
+}
+
+func (t *trigger) wait(op Operation, deadline time.Time) error {
 	t.lock.Lock()
 
 	if !t.isTriggered() {
-		if op == nil {
-			var lock sync.Mutex
-			lock.Lock()
-			t.registerAction(func(Trigger) { lock.Unlock() })
-			t.lock.Unlock()
-			lock.Lock()
-		} else {
-			op.Block(t.waiting, t.lock.Unlock)
+		if op != nil {
+			return op._blockUntil(t.waiting, t.lock.Unlock, deadline)
+		}
+		done := make(chan struct{})
+		t.registerAction(func(Trigger) { close(done) })
+		t.lock.Unlock()
+		if deadline.IsZero() {
+			<-done
+			return nil
+		}
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		select {
+		case <-done:
+		case <-timer.C:
+			return ErrTimeout
 		}
 	} else {
 		t.lock.Unlock()
 	}
+	return nil
 }
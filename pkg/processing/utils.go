@@ -4,6 +4,16 @@ import (
 	"strings"
 )
 
+// must stops the operation, if a blocking call without error result
+// is interrupted. Returning without the requested resource would
+// execute the following code unprotected. The panic is reported as
+// PanicError for the operation.
+func must(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
 func ElementName(typ string, names ...string) string {
 	name := strings.Join(names, ":")
 	if len(name) > 0 {